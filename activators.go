@@ -167,3 +167,27 @@ func (s *rectActicator) Derivative(dst, potentials []float64) {
 		}
 	}
 }
+
+func (l *linearActivation) Describe() Description {
+	return Description{Type: "linear", Params: map[string]float64{"a": l.a}}
+}
+
+func (s *sigmoidActivator) Describe() Description {
+	return Description{Type: "sigmoid"}
+}
+
+func (s *stepActicator) Describe() Description {
+	return Description{Type: "step"}
+}
+
+func (s *softmaxActicator) Describe() Description {
+	return Description{Type: "softmax"}
+}
+
+func (s *tanhActicator) Describe() Description {
+	return Description{Type: "tanh"}
+}
+
+func (s *rectActicator) Describe() Description {
+	return Description{Type: "rect"}
+}
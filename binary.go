@@ -0,0 +1,188 @@
+package neural
+
+import (
+	"math"
+	"sort"
+)
+
+// ROCPoint is a point of ROC curve, for samples classified as positive when output >= Threshold
+type ROCPoint struct {
+	Threshold         float64
+	FalsePositiveRate float64
+	TruePositiveRate  float64
+}
+
+// PrecisionRecallPoint is a point of precision-recall curve, for samples classified as positive when output >= Threshold
+type PrecisionRecallPoint struct {
+	Threshold float64
+	Precision float64
+	Recall    float64
+}
+
+// CalibrationBin describes samples with output in [From, To) range (last bin includes 1)
+type CalibrationBin struct {
+	From, To         float64
+	Samples          int
+	MeanOutput       float64 // Mean output of the network
+	FractionPositive float64 // Fraction of positive samples
+}
+
+// BinaryReport describes quality of binary classifier, network with a single output (e.g. with sigmoid activator)
+// giving probability of sample being positive. Sample is positive when its expected output is >= 0.5.
+//
+// Curves have a point for every distinct output, from the highest one. ROC curve starts with (0, 0) point
+// at +Inf threshold. Rates and precision are 0 when undefined (e.g. when there are no negative samples).
+//
+// Sample with NaN output is never classified as positive, so it's counted in rates of the curves,
+// but it does not have a point on them. It's not included in calibration either.
+type BinaryReport struct {
+	Samples    int
+	Positives  int
+	NaNOutputs int // Number of samples with NaN output of the network
+
+	ROC []ROCPoint
+	AUC float64 // Area under ROC curve
+
+	PrecisionRecall  []PrecisionRecallPoint
+	AveragePrecision float64 // Sum of precisions weighted by increase of recall
+
+	Calibration []CalibrationBin // Reliability diagram
+}
+
+// EvaluateBinaryClassification evaluates network on all samples of dataset to build BinaryReport.
+// Calibration is calculated for given number of equal bins in [0, 1] range.
+// Error is returned if network has more than one output or any sample could not be read,
+// *ExampleError if it does not fit the network and ErrNaNExpectedOutput if its Output is NaN.
+func EvaluateBinaryClassification(nn Evaluator, dataset Dataset, bins int) (BinaryReport, error) {
+	layers := nn.Layers()
+	if _, outputs := layers[len(layers)-1].Dimensions(); outputs != 1 {
+		return BinaryReport{}, ErrNotSingleOutput
+	}
+	if bins < 1 {
+		bins = 1
+	}
+
+	report := BinaryReport{
+		Samples:     dataset.Len(),
+		Calibration: make([]CalibrationBin, bins, bins),
+	}
+
+	scores := make([]binaryScore, 0, dataset.Len())
+	for i := 0; i < dataset.Len(); i++ {
+		sample, err := dataset.Example(i)
+		if err != nil {
+			return BinaryReport{}, err
+		}
+		if err := checkExample(nn, i, sample, true); err != nil {
+			return BinaryReport{}, err
+		}
+
+		if math.IsNaN(sample.Output[0]) {
+			return BinaryReport{}, ErrNaNExpectedOutput
+		}
+
+		score := binaryScore{
+			output:   nn.Evaluate(sample.Input)[0],
+			positive: sample.Output[0] >= 0.5,
+		}
+		if score.positive {
+			report.Positives++
+		}
+		if math.IsNaN(score.output) {
+			report.NaNOutputs++
+			continue
+		}
+		scores = append(scores, score)
+	}
+
+	report.curves(scores)
+	report.calibration(scores)
+	return report, nil
+}
+
+type binaryScore struct {
+	output   float64
+	positive bool
+}
+
+type byOutputDesc []binaryScore
+
+func (s byOutputDesc) Len() int           { return len(s) }
+func (s byOutputDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byOutputDesc) Less(i, j int) bool { return s[i].output > s[j].output }
+
+// curves calculates ROC and precision-recall curves with areas under them
+func (r *BinaryReport) curves(scores []binaryScore) {
+	sort.Sort(byOutputDesc(scores))
+	negatives := r.Samples - r.Positives
+
+	r.ROC = []ROCPoint{{Threshold: math.Inf(1)}}
+	r.PrecisionRecall = []PrecisionRecallPoint{}
+
+	truePositives, falsePositives := 0, 0
+	for i, score := range scores {
+		if score.positive {
+			truePositives++
+		} else {
+			falsePositives++
+		}
+
+		// Samples with the same output can not be separated by threshold
+		if i+1 < len(scores) && scores[i+1].output == score.output {
+			continue
+		}
+
+		previous := r.ROC[len(r.ROC)-1]
+		roc := ROCPoint{
+			Threshold:         score.output,
+			FalsePositiveRate: ratio(falsePositives, negatives),
+			TruePositiveRate:  ratio(truePositives, r.Positives),
+		}
+		r.ROC = append(r.ROC, roc)
+		r.AUC += (roc.FalsePositiveRate - previous.FalsePositiveRate) * (roc.TruePositiveRate + previous.TruePositiveRate) / 2
+
+		pr := PrecisionRecallPoint{
+			Threshold: score.output,
+			Precision: ratio(truePositives, truePositives+falsePositives),
+			Recall:    ratio(truePositives, r.Positives),
+		}
+		// Recall is the same as true positive rate
+		r.AveragePrecision += (pr.Recall - previous.TruePositiveRate) * pr.Precision
+		r.PrecisionRecall = append(r.PrecisionRecall, pr)
+	}
+}
+
+// calibration splits samples into bins by output of the network, outputs outside of [0, 1] go to the first or last bin
+func (r *BinaryReport) calibration(scores []binaryScore) {
+	bins := len(r.Calibration)
+	positives := make([]int, bins, bins)
+
+	for b := range r.Calibration {
+		r.Calibration[b].From = float64(b) / float64(bins)
+		r.Calibration[b].To = float64(b+1) / float64(bins)
+	}
+
+	for _, score := range scores {
+		position := score.output * float64(bins)
+		b := bins - 1
+		if position < 0 {
+			b = 0
+		} else if position < float64(bins-1) {
+			b = int(position)
+		}
+
+		r.Calibration[b].Samples++
+		r.Calibration[b].MeanOutput += score.output
+		if score.positive {
+			positives[b]++
+		}
+	}
+
+	for b := range r.Calibration {
+		bin := &r.Calibration[b]
+		if bin.Samples > 0 {
+			bin.MeanOutput /= float64(bin.Samples)
+		}
+		bin.FractionPositive = ratio(positives[b], bin.Samples)
+	}
+}
@@ -0,0 +1,127 @@
+package neural_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBinaryTestNetwork() neural.Evaluator {
+	// Output is the same as input
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{1}}, []float64{0})
+	return nn
+}
+
+func TestEvaluateBinaryClassification(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{0.4}, []float64{0}},
+		{[]float64{0.9}, []float64{1}},
+		{[]float64{0.7}, []float64{0}},
+		{[]float64{0.2}, []float64{0}},
+		{[]float64{0.8}, []float64{1}},
+		{[]float64{0.6}, []float64{1}},
+	}
+
+	report, err := neural.EvaluateBinaryClassification(newBinaryTestNetwork(), examples, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, report.Samples)
+	assert.Equal(t, 3, report.Positives)
+
+	expectedROC := []neural.ROCPoint{
+		{math.Inf(1), 0, 0},
+		{0.9, 0, 1.0 / 3},
+		{0.8, 0, 2.0 / 3},
+		{0.7, 1.0 / 3, 2.0 / 3},
+		{0.6, 1.0 / 3, 1},
+		{0.4, 2.0 / 3, 1},
+		{0.2, 1, 1},
+	}
+	assert.Equal(t, expectedROC, report.ROC)
+	assert.InDelta(t, 8.0/9, report.AUC, 0.000001)
+
+	expectedPR := []neural.PrecisionRecallPoint{
+		{0.9, 1, 1.0 / 3},
+		{0.8, 1, 2.0 / 3},
+		{0.7, 2.0 / 3, 2.0 / 3},
+		{0.6, 3.0 / 4, 1},
+		{0.4, 3.0 / 5, 1},
+		{0.2, 1.0 / 2, 1},
+	}
+	assert.Equal(t, expectedPR, report.PrecisionRecall)
+	assert.InDelta(t, 11.0/12, report.AveragePrecision, 0.000001)
+
+	assert.Len(t, report.Calibration, 2)
+	assert.Equal(t, 0.0, report.Calibration[0].From)
+	assert.Equal(t, 0.5, report.Calibration[0].To)
+	assert.Equal(t, 2, report.Calibration[0].Samples)
+	assert.InDelta(t, 0.3, report.Calibration[0].MeanOutput, 0.000001)
+	assert.Equal(t, 0.0, report.Calibration[0].FractionPositive)
+
+	assert.Equal(t, 0.5, report.Calibration[1].From)
+	assert.Equal(t, 1.0, report.Calibration[1].To)
+	assert.Equal(t, 4, report.Calibration[1].Samples)
+	assert.InDelta(t, 0.75, report.Calibration[1].MeanOutput, 0.000001)
+	assert.Equal(t, 0.75, report.Calibration[1].FractionPositive)
+}
+
+func TestEvaluateBinaryClassificationTies(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{0.5}, []float64{1}},
+		{[]float64{0.5}, []float64{0}},
+		{[]float64{1}, []float64{1}},
+	}
+
+	report, err := neural.EvaluateBinaryClassification(newBinaryTestNetwork(), examples, 4)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []neural.ROCPoint{{math.Inf(1), 0, 0}, {1, 0, 0.5}, {0.5, 1, 1}}, report.ROC)
+	assert.InDelta(t, 0.75, report.AUC, 0.000001)
+	assert.InDelta(t, 0.5+0.5*2/3.0, report.AveragePrecision, 0.000001)
+
+	// Output 1 goes to the last bin
+	samples := []int{}
+	for _, bin := range report.Calibration {
+		samples = append(samples, bin.Samples)
+	}
+	assert.Equal(t, []int{0, 0, 2, 1}, samples)
+}
+
+func TestEvaluateBinaryClassificationNotSingleOutput(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 2}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	_, err := neural.EvaluateBinaryClassification(nn, neural.SliceDataset{}, 10)
+	assert.Equal(t, neural.ErrNotSingleOutput, err)
+}
+
+func TestEvaluateBinaryClassificationOutputsOutOfRange(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{math.NaN()}, []float64{1}},
+		{[]float64{math.Inf(-1)}, []float64{0}},
+		{[]float64{-0.5}, []float64{0}},
+		{[]float64{1.5}, []float64{1}},
+		{[]float64{math.Inf(1)}, []float64{1}},
+	}
+
+	report, err := neural.EvaluateBinaryClassification(newBinaryTestNetwork(), examples, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Samples)
+	assert.Equal(t, 3, report.Positives)
+	assert.Equal(t, 1, report.NaNOutputs)
+
+	// Sample with NaN output is never classified as positive
+	last := report.ROC[len(report.ROC)-1]
+	assert.Equal(t, neural.ROCPoint{math.Inf(-1), 1, 2.0 / 3}, last)
+
+	assert.Equal(t, 2, report.Calibration[0].Samples)
+	assert.Equal(t, 2, report.Calibration[1].Samples)
+}
+
+func TestEvaluateBinaryClassificationInvalidExample(t *testing.T) {
+	_, err := neural.EvaluateBinaryClassification(newBinaryTestNetwork(), neural.SliceDataset{{[]float64{1}, []float64{}}}, 10)
+	assert.Equal(t, &neural.ExampleError{Index: 0, Validation: true, Inputs: 1, Outputs: 0, ExpectedInputs: 1, ExpectedOutputs: 1}, err)
+
+	_, err = neural.EvaluateBinaryClassification(newBinaryTestNetwork(), neural.SliceDataset{{[]float64{1}, []float64{math.NaN()}}}, 10)
+	assert.Equal(t, neural.ErrNaNExpectedOutput, err)
+}
@@ -0,0 +1,156 @@
+package neural
+
+import (
+	"math"
+
+	"github.com/mrfuxi/neural/mat"
+)
+
+// ClassificationReport describes quality of classification network, with one output per class.
+// Expected class of a sample is argmax of its Output, predicted class is argmax of network output.
+// Network output with NaN value does not predict any class, such sample is counted only in Unpredicted.
+type ClassificationReport struct {
+	Samples     int
+	Confusion   [][]int // Confusion[expected][predicted] is number of samples
+	Support     []int   // Number of samples of every expected class
+	Unpredicted int     // Number of samples with NaN in network output, they are wrong predictions of their expected class
+
+	// Per class metrics, 0 when undefined (e.g. precision of class never predicted)
+	Precision []float64
+	Recall    []float64
+	F1        []float64
+
+	// Macro averages are unweighted means of per class metrics (of all classes),
+	// micro averages are calculated from counts summed over all classes
+	MacroPrecision, MacroRecall, MacroF1 float64
+	MicroPrecision, MicroRecall, MicroF1 float64
+
+	Accuracy float64
+	TopK     []float64 // TopK[k-1] is fraction of samples with expected class among k highest outputs
+}
+
+// EvaluateClassification evaluates network on all samples of dataset to build ClassificationReport.
+// Top-k accuracy is calculated for every k from 1 to topK.
+// Error is returned if any sample could not be read, *ExampleError if it does not fit the network
+// and ErrNaNExpectedOutput if its Output has NaN value.
+func EvaluateClassification(nn Evaluator, dataset Dataset, topK int) (ClassificationReport, error) {
+	layers := nn.Layers()
+	_, classes := layers[len(layers)-1].Dimensions()
+	if topK > classes {
+		topK = classes
+	}
+	if topK < 0 {
+		topK = 0
+	}
+
+	report := ClassificationReport{
+		Samples:   dataset.Len(),
+		Confusion: make([][]int, classes, classes),
+		Support:   make([]int, classes, classes),
+		Precision: make([]float64, classes, classes),
+		Recall:    make([]float64, classes, classes),
+		F1:        make([]float64, classes, classes),
+		TopK:      make([]float64, topK, topK),
+	}
+	for c := range report.Confusion {
+		report.Confusion[c] = make([]int, classes, classes)
+	}
+
+	for i := 0; i < dataset.Len(); i++ {
+		sample, err := dataset.Example(i)
+		if err != nil {
+			return ClassificationReport{}, err
+		}
+
+		if err := checkExample(nn, i, sample, true); err != nil {
+			return ClassificationReport{}, err
+		}
+		expected := classOf(sample.Output)
+		if expected < 0 {
+			return ClassificationReport{}, ErrNaNExpectedOutput
+		}
+		report.Support[expected]++
+
+		output := nn.Evaluate(sample.Input)
+		predicted := classOf(output)
+		if predicted < 0 {
+			report.Unpredicted++
+			continue
+		}
+		report.Confusion[expected][predicted]++
+
+		for k := rank(output, expected); k < topK; k++ {
+			report.TopK[k]++
+		}
+	}
+
+	var correct int
+	for c := range report.Confusion {
+		predicted := 0
+		for e := range report.Confusion {
+			predicted += report.Confusion[e][c]
+		}
+		correct += report.Confusion[c][c]
+
+		report.Precision[c] = ratio(report.Confusion[c][c], predicted)
+		report.Recall[c] = ratio(report.Confusion[c][c], report.Support[c])
+		report.F1[c] = f1(report.Precision[c], report.Recall[c])
+
+		report.MacroPrecision += report.Precision[c] / float64(classes)
+		report.MacroRecall += report.Recall[c] / float64(classes)
+		report.MacroF1 += report.F1[c] / float64(classes)
+	}
+
+	// Every predicted sample has exactly one expected and one predicted class,
+	// so all false positives are also false negatives of other classes.
+	// Unpredicted samples are false negatives only.
+	report.Accuracy = ratio(correct, report.Samples)
+	report.MicroPrecision = ratio(correct, report.Samples-report.Unpredicted)
+	report.MicroRecall = report.Accuracy
+	report.MicroF1 = f1(report.MicroPrecision, report.MicroRecall)
+
+	if report.Samples > 0 {
+		mat.MulVectorByScalar(report.TopK, 1/float64(report.Samples))
+	}
+	return report, nil
+}
+
+// classOf finds first index of the highest value, or -1 if any value is NaN.
+// Unlike mat.ArgMax it works for values which are all negative.
+func classOf(values []float64) int {
+	class := 0
+	for i, value := range values {
+		if math.IsNaN(value) {
+			return -1
+		}
+		if value > values[class] {
+			class = i
+		}
+	}
+	return class
+}
+
+// rank finds position of class among outputs sorted from the highest. Ties are ordered like in classOf.
+func rank(output []float64, class int) int {
+	position := 0
+	for i, value := range output {
+		if value > output[class] || (value == output[class] && i < class) {
+			position++
+		}
+	}
+	return position
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func f1(precision, recall float64) float64 {
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
@@ -0,0 +1,101 @@
+package neural_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateClassification(t *testing.T) {
+	// Network passes input as output
+	nn := neural.NewNeuralNetwork([]int{3, 3}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}, []float64{0, 0, 0})
+
+	examples := neural.SliceDataset{
+		{[]float64{0.9, 0.1, 0}, []float64{1, 0, 0}},
+		{[]float64{0.2, 0.7, 0.1}, []float64{1, 0, 0}},
+		{[]float64{0, 0, 1}, []float64{1, 0, 0}},
+		{[]float64{0.1, 0.8, 0.1}, []float64{0, 1, 0}},
+		{[]float64{0.3, 0.2, 0.5}, []float64{0, 1, 0}},
+		{[]float64{0.1, 0.1, 0.8}, []float64{0, 0, 1}},
+		{[]float64{-0.6, -0.9, -0.7}, []float64{0, 0, 1}},
+	}
+
+	report, err := neural.EvaluateClassification(nn, examples, 5)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 7, report.Samples)
+	assert.Equal(t, [][]int{{1, 1, 1}, {0, 1, 1}, {1, 0, 1}}, report.Confusion)
+	assert.Equal(t, []int{3, 2, 2}, report.Support)
+
+	assert.InDeltaSlice(t, []float64{1.0 / 2, 1.0 / 2, 1.0 / 3}, report.Precision, 0.000001)
+	assert.InDeltaSlice(t, []float64{1.0 / 3, 1.0 / 2, 1.0 / 2}, report.Recall, 0.000001)
+	assert.InDeltaSlice(t, []float64{0.4, 0.5, 0.4}, report.F1, 0.000001)
+
+	assert.InDelta(t, 4.0/9, report.MacroPrecision, 0.000001)
+	assert.InDelta(t, 4.0/9, report.MacroRecall, 0.000001)
+	assert.InDelta(t, 1.3/3, report.MacroF1, 0.000001)
+
+	assert.InDelta(t, 3.0/7, report.Accuracy, 0.000001)
+	assert.InDelta(t, 3.0/7, report.MicroPrecision, 0.000001)
+	assert.InDelta(t, 3.0/7, report.MicroRecall, 0.000001)
+	assert.InDelta(t, 3.0/7, report.MicroF1, 0.000001)
+
+	// Limited to number of classes
+	assert.InDeltaSlice(t, []float64{3.0 / 7, 6.0 / 7, 1}, report.TopK, 0.000001)
+}
+
+func TestEvaluateClassificationUndefinedMetrics(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 2}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{1, 0}, {0, 1}}, []float64{0, 0})
+
+	// Second class is never expected nor predicted
+	examples := neural.SliceDataset{{[]float64{1, 0}, []float64{1, 0}}}
+
+	report, err := neural.EvaluateClassification(nn, examples, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 0}, report.Precision)
+	assert.Equal(t, []float64{1, 0}, report.Recall)
+	assert.Equal(t, []float64{1, 0}, report.F1)
+	assert.Equal(t, 0.5, report.MacroF1)
+	assert.Equal(t, 1.0, report.MicroF1)
+	assert.Equal(t, []float64{1}, report.TopK)
+}
+
+func TestEvaluateClassificationNaNOutput(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 2}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{1, 0}, {0, 1}}, []float64{0, 0})
+
+	examples := neural.SliceDataset{
+		{[]float64{1, 0}, []float64{1, 0}},
+		{[]float64{math.NaN(), 0}, []float64{1, 0}},
+		{[]float64{0, 1}, []float64{0, 1}},
+		{[]float64{-2, -1}, []float64{0, 1}},
+	}
+
+	report, err := neural.EvaluateClassification(nn, examples, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Samples)
+	assert.Equal(t, 1, report.Unpredicted)
+	assert.Equal(t, [][]int{{1, 0}, {0, 2}}, report.Confusion)
+	assert.Equal(t, []int{2, 2}, report.Support)
+	assert.Equal(t, []float64{0.5, 1}, report.Recall)
+	assert.Equal(t, 0.75, report.Accuracy)
+	assert.Equal(t, 1.0, report.MicroPrecision)
+	assert.Equal(t, []float64{0.75, 0.75}, report.TopK)
+}
+
+func TestEvaluateClassificationInvalidExamples(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 2}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+
+	_, err := neural.EvaluateClassification(nn, neural.SliceDataset{
+		{[]float64{1, 0}, []float64{1, 0}},
+		{[]float64{1, 0}, []float64{1}},
+	}, 1)
+	assert.Equal(t, &neural.ExampleError{Index: 1, Validation: true, Inputs: 2, Outputs: 1, ExpectedInputs: 2, ExpectedOutputs: 2}, err)
+
+	_, err = neural.EvaluateClassification(nn, neural.SliceDataset{{[]float64{1, 0}, []float64{math.NaN(), 1}}}, 1)
+	assert.Equal(t, neural.ErrNaNExpectedOutput, err)
+}
@@ -0,0 +1,255 @@
+package neural
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+
+	"github.com/mrfuxi/neural/mat"
+)
+
+// ConvolutionOptions describes shape of 2D convolution.
+//
+// Input and output signals are stored channel by channel, each channel row by row
+// (index = channel*height*width + y*width + x).
+// MNIST-like images (single channel, row by row) can be used directly.
+type ConvolutionOptions struct {
+	InputWidth    int
+	InputHeight   int
+	InputChannels int
+
+	KernelSize int // Width and height of the kernel
+	Stride     int // Step of the kernel, 1 if not set
+	Padding    int // Zeros added around every side of the input
+	Filters    int // Number of output channels
+}
+
+func (o ConvolutionOptions) stride() int {
+	if o.Stride <= 0 {
+		return 1
+	}
+	return o.Stride
+}
+
+// Validate checks if convolution layer with the options can be built for given number of inputs and neurons
+func (o ConvolutionOptions) Validate(inputs, neurons int) error {
+	if o.KernelSize <= 0 || o.Filters <= 0 || o.InputChannels <= 0 {
+		return errors.New("kernel size, filters and input channels of convolution have to be positive")
+	}
+	if o.KernelSize > o.InputWidth+2*o.Padding || o.KernelSize > o.InputHeight+2*o.Padding {
+		return errors.New("convolution kernel does not fit the input")
+	}
+	if inputs != o.Inputs() {
+		return fmt.Errorf("convolution expects %v inputs, got %v", o.Inputs(), inputs)
+	}
+	if neurons != o.Outputs() {
+		return fmt.Errorf("convolution produces %v outputs, got %v", o.Outputs(), neurons)
+	}
+	return nil
+}
+
+// OutputSize calculates shape of convolution output
+func (o ConvolutionOptions) OutputSize() (width, height, channels int) {
+	stride := o.stride()
+	width = (o.InputWidth+2*o.Padding-o.KernelSize)/stride + 1
+	height = (o.InputHeight+2*o.Padding-o.KernelSize)/stride + 1
+	return width, height, o.Filters
+}
+
+// Inputs is number of input values expected by convolution layer
+func (o ConvolutionOptions) Inputs() int {
+	return o.InputWidth * o.InputHeight * o.InputChannels
+}
+
+// Outputs is number of output values (neurons) produced by convolution layer
+func (o ConvolutionOptions) Outputs() int {
+	width, height, channels := o.OutputSize()
+	return width * height * channels
+}
+
+type convolutionalLayer struct {
+	activator Activator
+	weights   [][]float64 // row per filter, kernel values of all input channels in a row
+	biases    []float64   // bias per filter
+
+	ConvolutionOptions
+	outWidth  int
+	outHeight int
+}
+
+// NewConvolutionalLayer creates new neural network layer that applies 2D convolution to its input.
+// Every filter has own kernel (across all input channels) and bias, shared across all positions in the input.
+//
+// Number of inputs and neurons given when building a network has to match options.Inputs() and options.Outputs().
+func NewConvolutionalLayer(activator Activator, options ConvolutionOptions) LayerFactory {
+	return NewConvolutionalLayerFrom(activator, options, nil)
+}
+
+// NewConvolutionalLayerFrom works like NewConvolutionalLayer, but initial weights are taken
+// from given source of random numbers, so they can be reproduced. Global source is used if random is nil.
+func NewConvolutionalLayerFrom(activator Activator, options ConvolutionOptions, random *rand.Rand) LayerFactory {
+	return func(inputs, neurons int) Layer {
+		checkLayer(options.Validate(inputs, neurons))
+
+		kernelSize := options.InputChannels * options.KernelSize * options.KernelSize
+		weightsNorm := 1 / math.Sqrt(float64(kernelSize))
+		weights := mat.RandomMatrixFrom(random, options.Filters, kernelSize)
+		mat.MulMatrixByScalar(weights, weightsNorm)
+
+		outWidth, outHeight, _ := options.OutputSize()
+		options.Stride = options.stride()
+
+		return &convolutionalLayer{
+			weights:            weights,
+			biases:             mat.RandomVectorFrom(random, options.Filters),
+			activator:          activator,
+			ConvolutionOptions: options,
+			outWidth:           outWidth,
+			outHeight:          outHeight,
+		}
+	}
+}
+
+func (l *convolutionalLayer) Shapes() (weightsRow, weightsCol, biasesCol int) {
+	return l.Filters, l.InputChannels * l.KernelSize * l.KernelSize, l.Filters
+}
+
+func (l *convolutionalLayer) Dimensions() (inputs, outputs int) {
+	return l.Inputs(), l.Outputs()
+}
+
+// each calls fn for every pair of connected output and input values (and weight connecting them)
+func (l *convolutionalLayer) each(fn func(filter, out, weight, in int)) {
+	inPlane := l.InputWidth * l.InputHeight
+	outPlane := l.outWidth * l.outHeight
+	kernelPlane := l.KernelSize * l.KernelSize
+
+	for f := 0; f < l.Filters; f++ {
+		for oy := 0; oy < l.outHeight; oy++ {
+			for ox := 0; ox < l.outWidth; ox++ {
+				out := f*outPlane + oy*l.outWidth + ox
+				for c := 0; c < l.InputChannels; c++ {
+					for ky := 0; ky < l.KernelSize; ky++ {
+						iy := oy*l.Stride - l.Padding + ky
+						if iy < 0 || iy >= l.InputHeight {
+							continue
+						}
+						for kx := 0; kx < l.KernelSize; kx++ {
+							ix := ox*l.Stride - l.Padding + kx
+							if ix < 0 || ix >= l.InputWidth {
+								continue
+							}
+							fn(f, out, c*kernelPlane+ky*l.KernelSize+kx, c*inPlane+iy*l.InputWidth+ix)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (l *convolutionalLayer) Forward(dst, input []float64) {
+	outPlane := l.outWidth * l.outHeight
+	for f, bias := range l.biases {
+		for i := f * outPlane; i < (f+1)*outPlane; i++ {
+			dst[i] = bias
+		}
+	}
+
+	l.each(func(filter, out, weight, in int) {
+		dst[out] += l.weights[filter][weight] * input[in]
+	})
+}
+
+func (l *convolutionalLayer) Backward(dst, delta []float64) {
+	mat.ZeroVector(dst)
+
+	l.each(func(filter, out, weight, in int) {
+		dst[in] += l.weights[filter][weight] * delta[out]
+	})
+}
+
+func (l *convolutionalLayer) Gradients(weights [][]float64, biases []float64, delta, input []float64) {
+	mat.ZeroMatrix(weights)
+	mat.ZeroVector(biases)
+
+	outPlane := l.outWidth * l.outHeight
+	for i, d := range delta {
+		biases[i/outPlane] += d
+	}
+
+	l.each(func(filter, out, weight, in int) {
+		weights[filter][weight] += delta[out] * input[in]
+	})
+}
+
+func (l *convolutionalLayer) SetWeights(weights [][]float64, biases []float64) {
+	for r, row := range weights {
+		copy(l.weights[r], row)
+	}
+	copy(l.biases, biases)
+}
+
+func (l *convolutionalLayer) Weights() (weights [][]float64, biases []float64) {
+	return copyWeights(l.weights, l.biases)
+}
+
+func (l *convolutionalLayer) UpdateWeights(weights [][]float64, biases []float64, regularization float64) {
+	if regularization != 1 {
+		mat.MulMatrixByScalar(l.weights, regularization)
+	}
+	mat.SumMatrix(l.weights, weights)
+	mat.SumVector(l.biases, biases)
+}
+
+func (l *convolutionalLayer) Activator() Activator {
+	return l.activator
+}
+
+func (l *convolutionalLayer) Save(w io.Writer) error {
+	encoder := gob.NewEncoder(w)
+
+	if err := encoder.Encode(l.biases); err != nil {
+		return err
+	}
+
+	return encoder.Encode(l.weights)
+}
+
+func (l *convolutionalLayer) Load(r io.Reader) error {
+	decoder := gob.NewDecoder(r)
+
+	var biases []float64
+	if err := decoder.Decode(&biases); err != nil {
+		return err
+	}
+
+	var weights [][]float64
+	if err := decoder.Decode(&weights); err != nil {
+		return err
+	}
+
+	if err := checkShape(l, weights, biases); err != nil {
+		return err
+	}
+	l.biases, l.weights = biases, weights
+	return nil
+}
+
+func (l *convolutionalLayer) Describe() Description {
+	return Description{
+		Type: "convolution",
+		Params: map[string]float64{
+			"input_width":    float64(l.InputWidth),
+			"input_height":   float64(l.InputHeight),
+			"input_channels": float64(l.InputChannels),
+			"kernel_size":    float64(l.KernelSize),
+			"stride":         float64(l.stride()),
+			"padding":        float64(l.Padding),
+			"filters":        float64(l.Filters),
+		},
+	}
+}
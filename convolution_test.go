@@ -0,0 +1,148 @@
+package neural_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/mrfuxi/neural/mat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvolutionOutputSize(t *testing.T) {
+	testMatrix := []struct {
+		options               neural.ConvolutionOptions
+		width, height, inputs int
+		outputs               int
+	}{
+		{neural.ConvolutionOptions{InputWidth: 28, InputHeight: 28, InputChannels: 1, KernelSize: 5, Filters: 6}, 24, 24, 784, 3456},
+		{neural.ConvolutionOptions{InputWidth: 28, InputHeight: 28, InputChannels: 1, KernelSize: 5, Padding: 2, Filters: 6}, 28, 28, 784, 4704},
+		{neural.ConvolutionOptions{InputWidth: 5, InputHeight: 4, InputChannels: 2, KernelSize: 3, Stride: 2, Filters: 3}, 2, 1, 40, 6},
+	}
+
+	for _, example := range testMatrix {
+		width, height, channels := example.options.OutputSize()
+		assert.Equal(t, example.width, width)
+		assert.Equal(t, example.height, height)
+		assert.Equal(t, example.options.Filters, channels)
+		assert.Equal(t, example.inputs, example.options.Inputs())
+		assert.Equal(t, example.outputs, example.options.Outputs())
+	}
+}
+
+func TestConvolutionForward(t *testing.T) {
+	options := neural.ConvolutionOptions{InputWidth: 3, InputHeight: 3, InputChannels: 1, KernelSize: 2, Filters: 2}
+	layer := neural.NewConvolutionalLayer(neural.NewLinearActivator(1), options)(9, 8)
+	layer.SetWeights(
+		[][]float64{{1, 0, 0, 1}, {0, 1, 1, 0}},
+		[]float64{0, 10},
+	)
+
+	output := make([]float64, 8)
+	layer.Forward(output, []float64{
+		1, 2, 3,
+		4, 5, 6,
+		7, 8, 9,
+	})
+	assert.Equal(t, []float64{6, 8, 12, 14, 16, 18, 22, 24}, output)
+}
+
+func TestConvolutionForwardPaddingStride(t *testing.T) {
+	options := neural.ConvolutionOptions{InputWidth: 3, InputHeight: 3, InputChannels: 1, KernelSize: 3, Stride: 2, Padding: 1, Filters: 1}
+	layer := neural.NewConvolutionalLayer(neural.NewLinearActivator(1), options)(9, 4)
+	layer.SetWeights(
+		[][]float64{{1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		[]float64{0},
+	)
+
+	output := make([]float64, 4)
+	layer.Forward(output, []float64{
+		1, 2, 3,
+		4, 5, 6,
+		7, 8, 9,
+	})
+	assert.Equal(t, []float64{12, 16, 24, 28}, output)
+}
+
+func TestConvolutionBackward(t *testing.T) {
+	options := neural.ConvolutionOptions{InputWidth: 3, InputHeight: 3, InputChannels: 1, KernelSize: 2, Filters: 1}
+	layer := neural.NewConvolutionalLayer(neural.NewLinearActivator(1), options)(9, 4)
+	layer.SetWeights(
+		[][]float64{{1, 2, 3, 4}},
+		[]float64{0},
+	)
+
+	back := make([]float64, 9)
+	layer.Backward(back, []float64{1, 1, 1, 1})
+	assert.Equal(t, []float64{1, 3, 2, 4, 10, 6, 3, 7, 4}, back)
+}
+
+func TestConvolutionMismatchedShape(t *testing.T) {
+	options := neural.ConvolutionOptions{InputWidth: 3, InputHeight: 3, InputChannels: 1, KernelSize: 2, Filters: 1}
+	factory := neural.NewConvolutionalLayer(neural.NewLinearActivator(1), options)
+
+	assert.Panics(t, func() { factory(8, 4) })
+	assert.Panics(t, func() { factory(9, 5) })
+	assert.NotPanics(t, func() { factory(9, 4) })
+
+	assert.EqualError(t, options.Validate(8, 4), "convolution expects 9 inputs, got 8")
+	assert.EqualError(t, options.Validate(9, 5), "convolution produces 4 outputs, got 5")
+	assert.NoError(t, options.Validate(9, 4))
+
+	options.KernelSize = 4
+	assert.EqualError(t, options.Validate(9, 4), "convolution kernel does not fit the input")
+
+	_, err := neural.BuildNeuralNetwork([]int{8, 4}, factory)
+	assert.EqualError(t, err, "layer 0 (8 inputs, 4 neurons): convolution expects 9 inputs, got 8")
+}
+
+func TestConvolutionLearn(t *testing.T) {
+	// Detect vertical (1) and horizontal (0) lines on 3x3 image
+	testMatrix := []neural.TrainExample{
+		{[]float64{1, 0, 0, 1, 0, 0, 1, 0, 0}, []float64{1}},
+		{[]float64{0, 1, 0, 0, 1, 0, 0, 1, 0}, []float64{1}},
+		{[]float64{0, 0, 1, 0, 0, 1, 0, 0, 1}, []float64{1}},
+		{[]float64{1, 1, 1, 0, 0, 0, 0, 0, 0}, []float64{0}},
+		{[]float64{0, 0, 0, 1, 1, 1, 0, 0, 0}, []float64{0}},
+		{[]float64{0, 0, 0, 0, 0, 0, 1, 1, 1}, []float64{0}},
+	}
+
+	activator := neural.NewSigmoidActivator()
+	options := neural.ConvolutionOptions{InputWidth: 3, InputHeight: 3, InputChannels: 1, KernelSize: 2, Filters: 2}
+	nn := neural.NewNeuralNetwork(
+		[]int{options.Inputs(), options.Outputs(), 1},
+		neural.NewConvolutionalLayer(activator, options),
+		neural.NewFullyConnectedLayer(activator),
+	)
+
+	trainOptions := neural.TrainOptions{
+		Epochs:         2000,
+		MiniBatchSize:  6,
+		LearningRate:   3,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewCrossEntropyCost(),
+	}
+	neural.Train(nn, testMatrix, trainOptions)
+
+	for _, example := range testMatrix {
+		output := nn.Evaluate(example.Input)
+		assert.InDelta(t, example.Output[0], output[0], 0.2)
+	}
+}
+
+func TestConvolutionSaveLoad(t *testing.T) {
+	options := neural.ConvolutionOptions{InputWidth: 4, InputHeight: 4, InputChannels: 2, KernelSize: 3, Padding: 1, Filters: 3}
+	factory := neural.NewConvolutionalLayer(neural.NewSigmoidActivator(), options)
+	nn := neural.NewNeuralNetwork([]int{options.Inputs(), options.Outputs()}, factory)
+	input := mat.RandomVector(options.Inputs())
+	expected := nn.Evaluate(input)
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.Save(nn, buffer))
+
+	newNn := neural.NewNeuralNetwork([]int{options.Inputs(), options.Outputs()}, factory)
+	assert.NotEqual(t, expected, newNn.Evaluate(input))
+
+	assert.NoError(t, neural.Load(newNn, buffer))
+	assert.Equal(t, expected, newNn.Evaluate(input))
+}
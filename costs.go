@@ -94,3 +94,15 @@ func (c *logLikelihoodCost) CostDerivative(dst, output, desired, potentials []fl
 func NewLogLikelihoodCost() CostCostDerrivative {
 	return &logLikelihoodCost{}
 }
+
+func (q *quadraticCost) Describe() Description {
+	return Description{Type: "quadratic"}
+}
+
+func (c *corssEntropyCost) Describe() Description {
+	return Description{Type: "cross-entropy"}
+}
+
+func (c *logLikelihoodCost) Describe() Description {
+	return Description{Type: "log-likelihood"}
+}
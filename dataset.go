@@ -0,0 +1,120 @@
+package neural
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// Dataset gives access to examples without keeping all of them in memory.
+// Examples are numbered from 0 to Len()-1. Example can be called concurrently.
+type Dataset interface {
+	Len() int
+	Example(i int) (TrainExample, error)
+}
+
+// Shuffler is implemented by datasets which can change order of own examples.
+// Training calls Shuffle at the beginning of every epoch and reads examples in order,
+// datasets without Shuffler are read in random order instead.
+type Shuffler interface {
+	Shuffle(random *rand.Rand)
+}
+
+// SliceDataset is Dataset of examples kept in memory
+type SliceDataset []TrainExample
+
+// Len returns number of examples
+func (d SliceDataset) Len() int {
+	return len(d)
+}
+
+// Example returns i-th example
+func (d SliceDataset) Example(i int) (TrainExample, error) {
+	return d[i], nil
+}
+
+// Shuffle changes order of examples in place. Global source is used if random is nil.
+func (d SliceDataset) Shuffle(random *rand.Rand) {
+	intn := rand.Intn
+	if random != nil {
+		intn = random.Intn
+	}
+
+	for i := range d {
+		j := intn(i + 1)
+		d[i], d[j] = d[j], d[i]
+	}
+}
+
+type binaryDataset struct {
+	reader          io.ReaderAt
+	inputs, outputs int
+	examples        int
+}
+
+// NewBinaryDataset creates Dataset reading examples from r (e.g. os.File) on demand, so it can be bigger than memory.
+// Every example is stored as inputs followed by outputs, encoded as little endian float64 values (see WriteBinaryDataset).
+// Size is the number of bytes available in r.
+func NewBinaryDataset(r io.ReaderAt, size int64, inputs, outputs int) (Dataset, error) {
+	if inputs <= 0 || outputs <= 0 {
+		return nil, ErrNeuronsCount
+	}
+
+	exampleSize := int64(8 * (inputs + outputs))
+	if size%exampleSize != 0 {
+		return nil, ErrBinaryDatasetSize
+	}
+
+	return &binaryDataset{
+		reader:   r,
+		inputs:   inputs,
+		outputs:  outputs,
+		examples: int(size / exampleSize),
+	}, nil
+}
+
+func (d *binaryDataset) Len() int {
+	return d.examples
+}
+
+func (d *binaryDataset) Example(i int) (TrainExample, error) {
+	values := make([]float64, d.inputs+d.outputs)
+	buffer := make([]byte, 8*len(values))
+	// ReaderAt may return io.EOF together with the last example
+	n, err := d.reader.ReadAt(buffer, int64(i)*int64(len(buffer)))
+	if n < len(buffer) {
+		return TrainExample{}, err
+	}
+
+	for v := range values {
+		values[v] = math.Float64frombits(binary.LittleEndian.Uint64(buffer[8*v:]))
+	}
+
+	return TrainExample{
+		Input:  values[:d.inputs:d.inputs],
+		Output: values[d.inputs:],
+	}, nil
+}
+
+// WriteBinaryDataset writes all examples of dataset to w in format read by NewBinaryDataset.
+// Values are written one by one, so w should be buffered.
+func WriteBinaryDataset(w io.Writer, dataset Dataset) error {
+	var buffer [8]byte
+	for i := 0; i < dataset.Len(); i++ {
+		example, err := dataset.Example(i)
+		if err != nil {
+			return err
+		}
+
+		for _, values := range [][]float64{example.Input, example.Output} {
+			for _, value := range values {
+				binary.LittleEndian.PutUint64(buffer[:], math.Float64bits(value))
+				if _, err := w.Write(buffer[:]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package neural_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+var datasetExamples = []neural.TrainExample{
+	{[]float64{0, 0}, []float64{0}},
+	{[]float64{1, 1}, []float64{0}},
+	{[]float64{0, 1}, []float64{1}},
+	{[]float64{1, 0}, []float64{1}},
+	{[]float64{0.5, -0.25}, []float64{0.75}},
+}
+
+func binaryDataset(t *testing.T, examples []neural.TrainExample) neural.Dataset {
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.WriteBinaryDataset(buffer, neural.SliceDataset(examples)))
+
+	dataset, err := neural.NewBinaryDataset(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), 2, 1)
+	assert.NoError(t, err)
+	return dataset
+}
+
+func TestSliceDatasetShuffle(t *testing.T) {
+	dataset := append(neural.SliceDataset(nil), datasetExamples...)
+	other := append(neural.SliceDataset(nil), datasetExamples...)
+
+	dataset.Shuffle(rand.New(rand.NewSource(1)))
+	other.Shuffle(rand.New(rand.NewSource(1)))
+
+	assert.Equal(t, other, dataset)
+	assert.Equal(t, len(datasetExamples), dataset.Len())
+	assert.ElementsMatch(t, datasetExamples, dataset)
+}
+
+func TestBinaryDataset(t *testing.T) {
+	dataset := binaryDataset(t, datasetExamples)
+	assert.Equal(t, len(datasetExamples), dataset.Len())
+
+	for i, expected := range datasetExamples {
+		example, err := dataset.Example(i)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, example)
+	}
+
+	_, err := dataset.Example(len(datasetExamples))
+	assert.Equal(t, io.EOF, err)
+}
+
+// eofReaderAt returns io.EOF together with data which reaches end of the reader, as ReaderAt is allowed to
+type eofReaderAt struct {
+	*bytes.Reader
+}
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	if err == nil && off+int64(n) == r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestBinaryDatasetEOFWithLastExample(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.WriteBinaryDataset(buffer, neural.SliceDataset(datasetExamples)))
+
+	dataset, err := neural.NewBinaryDataset(eofReaderAt{bytes.NewReader(buffer.Bytes())}, int64(buffer.Len()), 2, 1)
+	assert.NoError(t, err)
+
+	example, err := dataset.Example(len(datasetExamples) - 1)
+	assert.NoError(t, err)
+	assert.Equal(t, datasetExamples[len(datasetExamples)-1], example)
+
+	_, err = dataset.Example(len(datasetExamples))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBinaryDatasetErrors(t *testing.T) {
+	_, err := neural.NewBinaryDataset(bytes.NewReader(nil), 25, 2, 1)
+	assert.Equal(t, neural.ErrBinaryDatasetSize, err)
+
+	_, err = neural.NewBinaryDataset(bytes.NewReader(nil), 24, 0, 3)
+	assert.Equal(t, neural.ErrNeuronsCount, err)
+}
+
+func TestTrainDatasetSameAsSlice(t *testing.T) {
+	train := func(dataset neural.Dataset) []byte {
+		random := rand.New(rand.NewSource(7))
+		nn := neural.NewNeuralNetwork(
+			[]int{2, 4, 1},
+			neural.NewFullyConnectedLayerFrom(neural.NewSigmoidActivator(), random),
+			neural.NewFullyConnectedLayerFrom(neural.NewSigmoidActivator(), random),
+		)
+
+		options := neural.TrainOptions{
+			Epochs:         10,
+			MiniBatchSize:  2,
+			LearningRate:   0.5,
+			TrainerFactory: neural.NewBackpropagationTrainer,
+			Cost:           neural.NewQuadraticCost(),
+			Workers:        1,
+			Random:         random,
+		}
+		assert.NoError(t, neural.TrainDataset(context.Background(), nn, dataset, options))
+
+		buffer := new(bytes.Buffer)
+		assert.NoError(t, neural.Save(nn, buffer))
+		return buffer.Bytes()
+	}
+
+	// Slice is shuffled in place, binary dataset is read in shuffled order, both see examples in the same order
+	fromDisk := train(binaryDataset(t, datasetExamples))
+	fromSlice := train(append(neural.SliceDataset(nil), datasetExamples...))
+
+	assert.Equal(t, fromSlice, fromDisk)
+}
+
+func TestTrainDatasetErrors(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	options := neural.TrainOptions{
+		Epochs:         1,
+		MiniBatchSize:  1,
+		LearningRate:   0.5,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+	}
+
+	// Reading beyond the data
+	dataset, err := neural.NewBinaryDataset(bytes.NewReader(make([]byte, 24)), 48, 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, io.EOF, neural.TrainDataset(context.Background(), nn, dataset, options))
+
+	// Examples not matching the network
+	dataset, err = neural.NewBinaryDataset(bytes.NewReader(make([]byte, 24)), 24, 1, 2)
+	assert.NoError(t, err)
+	assert.IsType(t, &neural.ExampleError{}, neural.TrainDataset(context.Background(), nn, dataset, options))
+
+	assert.Equal(t, neural.ErrNoExamples, neural.TrainDataset(context.Background(), nn, neural.SliceDataset(nil), options))
+
+	options.EarlyStopping = &neural.EarlyStopping{Patience: 1}
+	options.ValidationDataset = neural.SliceDataset(nil)
+	assert.Equal(t, neural.ErrNoValidationExamples, neural.TrainDataset(context.Background(), nn, binaryDataset(t, datasetExamples), options))
+}
+
+func TestCalculateDatasetCorrectness(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()), neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	cost := neural.NewQuadraticCost()
+
+	expectedCost, expectedErrors := neural.CalculateCorrectness(nn, cost, datasetExamples)
+	avgCost, errors, err := neural.CalculateDatasetCorrectness(nn, cost, binaryDataset(t, datasetExamples))
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCost, avgCost)
+	assert.Equal(t, expectedErrors, errors)
+}
+
+func TestTrainDatasetInvalidValidationExample(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+
+	// Validation examples with 3 inputs, read only at the end of the epoche
+	validation, err := neural.NewBinaryDataset(bytes.NewReader(make([]byte, 64)), 64, 3, 1)
+	assert.NoError(t, err)
+
+	options := neural.TrainOptions{
+		Epochs:            1,
+		MiniBatchSize:     1,
+		LearningRate:      0.5,
+		TrainerFactory:    neural.NewBackpropagationTrainer,
+		Cost:              neural.NewQuadraticCost(),
+		ValidationDataset: validation,
+		EpocheCallback:    func(neural.EpocheStats) bool { return false },
+	}
+	err = neural.TrainDataset(context.Background(), nn, binaryDataset(t, datasetExamples), options)
+	assert.Equal(t, &neural.ExampleError{Index: 0, Validation: true, Inputs: 3, Outputs: 1, ExpectedInputs: 2, ExpectedOutputs: 1}, err)
+
+	_, _, err = neural.CalculateDatasetCorrectness(nn, neural.NewQuadraticCost(), validation)
+	assert.IsType(t, &neural.ExampleError{}, err)
+	assert.Panics(t, func() {
+		neural.CalculateCorrectness(nn, neural.NewQuadraticCost(), []neural.TrainExample{{[]float64{1}, []float64{1}}})
+	})
+}
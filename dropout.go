@@ -0,0 +1,102 @@
+package neural
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+type dropoutLayer struct {
+	weightlessLayer
+	probability float64
+	size        int
+
+	// seeds every copy gets own random from, global source if nil
+	seeds *rand.Rand
+
+	// mode, mask and random are set in copies made for trainers (see SampleLayer)
+	mode   Mode
+	mask   []float64
+	random *rand.Rand
+}
+
+// NewDropoutLayer creates layer that randomly drops (sets to 0) part of its inputs while training.
+// Every input is dropped with given probability, remaining ones are scaled by 1/(1-probability),
+// so that in Inference mode layer passes input without any changes.
+//
+// Number of inputs and neurons given when building a network has to be equal.
+func NewDropoutLayer(probability float64) LayerFactory {
+	return NewDropoutLayerFrom(probability, nil)
+}
+
+// NewDropoutLayerFrom works like NewDropoutLayer, but dropped inputs are chosen using given source of random numbers,
+// so they can be reproduced. Global source is used if random is nil.
+func NewDropoutLayerFrom(probability float64, random *rand.Rand) LayerFactory {
+	return func(inputs, neurons int) Layer {
+		if probability < 0 || probability >= 1 {
+			checkLayer(errors.New("dropout probability has to be in [0, 1) range"))
+		}
+		if inputs != neurons {
+			checkLayer(fmt.Errorf("dropout needs the same number of inputs and neurons, got %v and %v", inputs, neurons))
+		}
+
+		return &dropoutLayer{
+			probability: probability,
+			size:        inputs,
+			seeds:       random,
+		}
+	}
+}
+
+func (l *dropoutLayer) ForSample(mode Mode) Layer {
+	var seed int64
+	if l.seeds != nil {
+		seed = l.seeds.Int63()
+	} else {
+		seed = rand.Int63()
+	}
+
+	return &dropoutLayer{
+		probability: l.probability,
+		size:        l.size,
+		mode:        mode,
+		mask:        make([]float64, l.size, l.size),
+		random:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (l *dropoutLayer) Dimensions() (inputs, outputs int) {
+	return l.size, l.size
+}
+
+func (l *dropoutLayer) Forward(dst, input []float64) {
+	if l.mode != Training {
+		copy(dst, input)
+		return
+	}
+
+	scale := 1 / (1 - l.probability)
+	for i, inputValue := range input {
+		if l.random.Float64() < l.probability {
+			l.mask[i] = 0
+		} else {
+			l.mask[i] = scale
+		}
+		dst[i] = inputValue * l.mask[i]
+	}
+}
+
+func (l *dropoutLayer) Backward(dst, delta []float64) {
+	if l.mode != Training {
+		copy(dst, delta)
+		return
+	}
+
+	for i, deltaValue := range delta {
+		dst[i] = deltaValue * l.mask[i]
+	}
+}
+
+func (l *dropoutLayer) Describe() Description {
+	return Description{Type: "dropout", Params: map[string]float64{"probability": l.probability}}
+}
@@ -0,0 +1,114 @@
+package neural_test
+
+import (
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropoutInference(t *testing.T) {
+	layer := neural.NewDropoutLayer(0.5)(4, 4)
+	input := []float64{1, 2, 3, 4}
+
+	output := make([]float64, 4)
+	layer.Forward(output, input)
+	assert.Equal(t, input, output)
+
+	back := make([]float64, 4)
+	layer.Backward(back, input)
+	assert.Equal(t, input, back)
+}
+
+func TestDropoutTraining(t *testing.T) {
+	size := 10000
+	layer := neural.NewDropoutLayer(0.2)(size, size).(neural.SampleLayer).ForSample(neural.Training)
+
+	input := make([]float64, size)
+	for i := range input {
+		input[i] = 1
+	}
+
+	output := make([]float64, size)
+	layer.Forward(output, input)
+
+	dropped := 0
+	for _, val := range output {
+		if val == 0 {
+			dropped++
+		} else {
+			assert.InDelta(t, 1.25, val, 0.0000001)
+		}
+	}
+	assert.InDelta(t, 0.2, float64(dropped)/float64(size), 0.02)
+
+	// Delta goes back through the same units as input went forward
+	back := make([]float64, size)
+	layer.Backward(back, input)
+	assert.Equal(t, output, back)
+}
+
+func TestDropoutMismatchedShape(t *testing.T) {
+	assert.Panics(t, func() { neural.NewDropoutLayer(0.5)(4, 3) })
+	assert.Panics(t, func() { neural.NewDropoutLayer(1)(4, 4) })
+	assert.NotPanics(t, func() { neural.NewDropoutLayer(0)(4, 4) })
+}
+
+func TestDropoutTrainerMasksUnits(t *testing.T) {
+	linear := neural.NewLinearActivator(1)
+	nn := neural.NewNeuralNetwork(
+		[]int{4, 4, 4},
+		neural.NewFullyConnectedLayer(linear),
+		neural.NewDropoutLayer(0.5),
+	)
+	nn.Layers()[0].SetWeights([][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}, []float64{0, 0, 0, 0})
+
+	sample := neural.TrainExample{Input: []float64{1, 2, 3, 4}, Output: []float64{0, 0, 0, 0}}
+	trainer := neural.NewBackpropagationTrainer(nn, neural.NewQuadraticCost())
+	weightUpdates := neural.NewWeightUpdates(nn)
+
+	for i := 0; i < 10; i++ {
+		trainer.Process(sample, &weightUpdates)
+
+		// Output error of kept units is 2*input and it's scaled again on the way back
+		for j, delta := range weightUpdates.Biases[0] {
+			if delta != 0 {
+				assert.Equal(t, 4*sample.Input[j], delta)
+			}
+		}
+	}
+
+	// Evaluation is not affected
+	assert.Equal(t, sample.Input, nn.Evaluate(sample.Input))
+}
+
+func TestLearnXORDropout(t *testing.T) {
+	testMatrix := []neural.TrainExample{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 1}, []float64{0}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+	}
+
+	activator := neural.NewSigmoidActivator()
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 20, 20, 1},
+		neural.NewFullyConnectedLayer(activator),
+		neural.NewDropoutLayer(0.1),
+		neural.NewFullyConnectedLayer(activator),
+	)
+
+	options := neural.TrainOptions{
+		Epochs:         2000,
+		MiniBatchSize:  4,
+		LearningRate:   1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewCrossEntropyCost(),
+	}
+	neural.Train(nn, testMatrix, options)
+
+	for _, example := range testMatrix {
+		output := nn.Evaluate(example.Input)
+		assert.InDelta(t, example.Output[0], output[0], 0.2)
+	}
+}
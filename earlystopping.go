@@ -0,0 +1,90 @@
+package neural
+
+import "bytes"
+
+// Metric selects how network is judged on validation examples (see CalculateCorrectness)
+type Metric int
+
+const (
+	// CostMetric is average cost of validation examples
+	CostMetric Metric = iota
+	// ErrorRateMetric is fraction of validation examples with wrong answer (argmax of output)
+	ErrorRateMetric
+)
+
+// EarlyStopping defines when training should stop before reaching all epochs.
+// After every epoch Metric is calculated for validation examples, and when it does not improve
+// for Patience epochs in a row training stops.
+type EarlyStopping struct {
+	Metric      Metric
+	Patience    int     // Number of epochs without improvement before stopping
+	MinDelta    float64 // Minimal decrease of the metric treated as an improvement
+	RestoreBest bool    // Restore weights from the epoch with the best metric when training ends, for any reason
+	Cost        Cost    // Used to calculate the metric, TrainOptions.Cost if not set
+}
+
+// earlyStopper tracks validation metric across epochs
+type earlyStopper struct {
+	EarlyStopping
+
+	best     float64
+	observed bool
+	waiting  int
+	snapshot bytes.Buffer
+}
+
+func newEarlyStopper(options TrainOptions) (*earlyStopper, error) {
+	if options.EarlyStopping == nil {
+		return nil, nil
+	}
+	if options.validationDataset().Len() == 0 {
+		return nil, ErrNoValidationExamples
+	}
+
+	e := earlyStopper{
+		EarlyStopping: *options.EarlyStopping,
+	}
+
+	if e.Cost == nil {
+		cost, ok := options.Cost.(Cost)
+		if !ok {
+			return nil, ErrNoValidationCost
+		}
+		e.Cost = cost
+	}
+
+	return &e, nil
+}
+
+// observe checks if training should be stopped after current epoch, judging by its validation metrics.
+// Weights of the best epoch are kept if they are to be restored.
+func (e *earlyStopper) observe(network Evaluator, stats EpocheStats) (stop bool, err error) {
+	metric := stats.ValidationCost
+	if e.Metric == ErrorRateMetric {
+		metric = stats.ValidationErrorRate
+	}
+
+	if !e.observed || metric < e.best-e.MinDelta {
+		e.best = metric
+		e.observed = true
+		e.waiting = 0
+
+		if e.RestoreBest {
+			e.snapshot.Reset()
+			return false, Save(network, &e.snapshot)
+		}
+		return false, nil
+	}
+
+	e.waiting++
+	return e.waiting >= e.Patience, nil
+}
+
+// restore loads weights of the best epoch into the network, if they are to be restored.
+// It's safe to call on nil earlyStopper.
+func (e *earlyStopper) restore(network Evaluator) error {
+	if e == nil || !e.RestoreBest || !e.observed {
+		return nil
+	}
+	return Load(network, bytes.NewReader(e.snapshot.Bytes()))
+}
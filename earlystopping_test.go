@@ -0,0 +1,133 @@
+package neural_test
+
+import (
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEarlyStoppingTestNetwork() neural.Evaluator {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{0}}, []float64{0})
+	return nn
+}
+
+func TestEarlyStoppingNoImprovement(t *testing.T) {
+	nn := newEarlyStoppingTestNetwork()
+	examples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+
+	epochs := 0
+	options := neural.TrainOptions{
+		Epochs:             100,
+		MiniBatchSize:      1,
+		LearningRate:       0,
+		TrainerFactory:     neural.NewBackpropagationTrainer,
+		Cost:               neural.NewQuadraticCost(),
+		ValidationExamples: examples,
+		EarlyStopping:      &neural.EarlyStopping{Patience: 3},
+		EpocheCallback: func(stats neural.EpocheStats) bool {
+			epochs = stats.Epoche
+			return false
+		},
+	}
+	assert.NoError(t, neural.Train(nn, examples, options))
+
+	assert.Equal(t, 4, epochs)
+}
+
+func TestEarlyStoppingRestoreBest(t *testing.T) {
+	nn := newEarlyStoppingTestNetwork()
+	trainExamples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+	validationExamples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{0.3}}}
+
+	outputs := [][]float64{}
+	options := neural.TrainOptions{
+		Epochs:             100,
+		MiniBatchSize:      1,
+		LearningRate:       0.1,
+		TrainerFactory:     neural.NewBackpropagationTrainer,
+		Cost:               neural.NewQuadraticCost(),
+		ValidationExamples: validationExamples,
+		EarlyStopping:      &neural.EarlyStopping{Patience: 2, RestoreBest: true},
+		EpocheCallback: func(stats neural.EpocheStats) bool {
+			outputs = append(outputs, nn.Evaluate(validationExamples[0].Input))
+			return false
+		},
+	}
+	assert.NoError(t, neural.Train(nn, trainExamples, options))
+
+	// Output goes 0.2, 0.36, 0.488: second epoch is the closest to validation
+	assert.Len(t, outputs, 4)
+	assert.Equal(t, outputs[1], nn.Evaluate(validationExamples[0].Input))
+}
+
+func TestEarlyStoppingRestoreBestWhenTrainingEnds(t *testing.T) {
+	trainExamples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+	validationExamples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{0.3}}}
+
+	for _, stopByCallback := range []bool{false, true} {
+		nn := newEarlyStoppingTestNetwork()
+		outputs := [][]float64{}
+		options := neural.TrainOptions{
+			Epochs:             4,
+			MiniBatchSize:      1,
+			LearningRate:       0.1,
+			TrainerFactory:     neural.NewBackpropagationTrainer,
+			Cost:               neural.NewQuadraticCost(),
+			ValidationExamples: validationExamples,
+			EarlyStopping:      &neural.EarlyStopping{Patience: 10, RestoreBest: true},
+			EpocheCallback: func(stats neural.EpocheStats) bool {
+				outputs = append(outputs, nn.Evaluate(validationExamples[0].Input))
+				return stopByCallback && stats.Epoche == 3
+			},
+		}
+		assert.NoError(t, neural.Train(nn, trainExamples, options))
+
+		// Patience does not run out, second epoch is still the best one
+		assert.Equal(t, outputs[1], nn.Evaluate(validationExamples[0].Input))
+		assert.NotEqual(t, outputs[len(outputs)-1], nn.Evaluate(validationExamples[0].Input))
+	}
+}
+
+func TestEarlyStoppingErrorRate(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 2}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	nn.Layers()[0].SetWeights([][]float64{{1, 0}, {0, 1}}, []float64{0, 0})
+	examples := []neural.TrainExample{
+		{Input: []float64{1, 0}, Output: []float64{1, 0}},
+		{Input: []float64{0, 1}, Output: []float64{0, 1}},
+	}
+
+	// Cost keeps improving, but there are no errors from the beginning
+	epochs := 0
+	options := neural.TrainOptions{
+		Epochs:             100,
+		MiniBatchSize:      2,
+		LearningRate:       1,
+		TrainerFactory:     neural.NewBackpropagationTrainer,
+		Cost:               neural.NewCrossEntropyCost(),
+		ValidationExamples: examples,
+		EarlyStopping:      &neural.EarlyStopping{Metric: neural.ErrorRateMetric, Patience: 5},
+		EpocheCallback: func(stats neural.EpocheStats) bool {
+			epochs = stats.Epoche
+			return false
+		},
+	}
+	assert.NoError(t, neural.Train(nn, examples, options))
+
+	assert.Equal(t, 6, epochs)
+}
+
+func TestEarlyStoppingWithoutValidation(t *testing.T) {
+	nn := newEarlyStoppingTestNetwork()
+	examples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+
+	options := neural.TrainOptions{
+		Epochs:         100,
+		MiniBatchSize:  1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+		EarlyStopping:  &neural.EarlyStopping{Patience: 3},
+	}
+	assert.Equal(t, neural.ErrNoValidationExamples, neural.Train(nn, examples, options))
+}
@@ -0,0 +1,161 @@
+package neural
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when network, training options or examples are not valid
+var (
+	ErrLayersCount          = errors.New("neuron counts does not match layers count")
+	ErrNeuronsCount         = errors.New("neuron counts have to be positive")
+	ErrNoNetwork            = errors.New("network is not set")
+	ErrNoExamples           = errors.New("no examples to train on")
+	ErrInvalidMiniBatchSize = errors.New("mini-batch size has to be positive")
+	ErrNoTrainerFactory     = errors.New("trainer factory is not set")
+	ErrNoCost               = errors.New("cost is not set")
+	ErrNoValidationExamples = errors.New("early stopping and schedule observing validation metric need validation examples")
+	ErrNoValidationCost     = errors.New("early stopping and schedule observing validation metric need Cost to calculate it")
+	ErrNoBatchTrainer       = errors.New("network with batch layer needs trainer implementing BatchTrainer")
+	ErrBinaryDatasetSize    = errors.New("binary dataset size is not a multiple of example size")
+	ErrNotSingleOutput      = errors.New("binary classifier has to have a single output")
+	ErrNaNExpectedOutput    = errors.New("expected output of example has NaN value")
+	ErrCostNotDescribable   = errors.New("cost can not be described")
+)
+
+// Errors returned when persisted network or model can not be loaded
+var (
+	ErrBadMagic         = errors.New("data does not start with magic number of a network file")
+	ErrLegacyFile       = errors.New("network file was saved by older version, without header")
+	ErrTruncated        = errors.New("network file is truncated")
+	ErrMalformedFile    = errors.New("network file is malformed")
+	ErrNoArchitecture   = errors.New("network file does not contain architecture of a model")
+	ErrSavedLayersCount = errors.New("number of saved layers does not match the network")
+)
+
+// LayerError describes layer which could not be built by its LayerFactory
+type LayerError struct {
+	Layer           int
+	Inputs, Neurons int
+	Reason          string
+}
+
+func (e *LayerError) Error() string {
+	return fmt.Sprintf("layer %v (%v inputs, %v neurons): %v", e.Layer, e.Inputs, e.Neurons, e.Reason)
+}
+
+// ExampleError describes example which does not match shape of the network
+type ExampleError struct {
+	Index      int
+	Validation bool // Example comes from ValidationExamples or ValidationDataset
+
+	Inputs, Outputs                 int
+	ExpectedInputs, ExpectedOutputs int
+}
+
+func (e *ExampleError) Error() string {
+	kind := "training"
+	if e.Validation {
+		kind = "validation"
+	}
+	return fmt.Sprintf(
+		"%v example %v has %v inputs and %v outputs, network expects %v inputs and %v outputs",
+		kind, e.Index, e.Inputs, e.Outputs, e.ExpectedInputs, e.ExpectedOutputs,
+	)
+}
+
+// UnknownTypeError describes activator, cost or layer type of a loaded model which is not known
+type UnknownTypeError struct {
+	Kind string // activator, cost or layer
+	Type string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("unknown %v type %q", e.Kind, e.Type)
+}
+
+// ParamError describes missing or invalid parameter of activator, cost or layer
+type ParamError struct {
+	Kind   string // activator, cost or layer
+	Type   string
+	Param  string
+	Reason string // Why the parameter is not valid, empty if it's missing
+}
+
+func (e *ParamError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%v %q has invalid parameter %q: %v", e.Kind, e.Type, e.Param, e.Reason)
+	}
+	return fmt.Sprintf("%v %q needs parameter %q", e.Kind, e.Type, e.Param)
+}
+
+// VersionError describes persisted network of unsupported format version
+type VersionError struct {
+	Version int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("unsupported network file version %v", e.Version)
+}
+
+// ChecksumError describes section of persisted network which does not match its checksum
+type ChecksumError struct {
+	Layer int // -1 for architecture of a model
+}
+
+func (e *ChecksumError) Error() string {
+	if e.Layer < 0 {
+		return "checksum of model architecture does not match"
+	}
+	return fmt.Sprintf("checksum of layer %v does not match", e.Layer)
+}
+
+// ShapeError describes persisted layer which does not match shape of the layer it's loaded into
+type ShapeError struct {
+	Layer int
+
+	WeightsRow, WeightsCol, BiasesCol                int // Shapes of the layer
+	SavedWeightsRow, SavedWeightsCol, SavedBiasesCol int
+}
+
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf(
+		"layer %v has %vx%v weights and %v biases, saved layer has %vx%v weights and %v biases",
+		e.Layer, e.WeightsRow, e.WeightsCol, e.BiasesCol, e.SavedWeightsRow, e.SavedWeightsCol, e.SavedBiasesCol,
+	)
+}
+
+// ArchitectureError describes layer of the network which is different than the layer of loaded model (see Load)
+type ArchitectureError struct {
+	Layer   int
+	Network LayerArchitecture
+	Saved   LayerArchitecture
+}
+
+func (e *ArchitectureError) Error() string {
+	return fmt.Sprintf("layer %v is %v, saved layer is %v", e.Layer, describeLayer(e.Network), describeLayer(e.Saved))
+}
+
+func describeLayer(layer LayerArchitecture) string {
+	return fmt.Sprintf(
+		"%v%v with %v%v activator (%v inputs, %v neurons)",
+		layer.Layer.Type, describeParams(layer.Layer), layer.Activator.Type, describeParams(layer.Activator), layer.Inputs, layer.Neurons,
+	)
+}
+
+func describeParams(description Description) string {
+	if len(description.Params) == 0 {
+		return ""
+	}
+	return fmt.Sprint(description.Params)
+}
+
+// StateError describes missing or invalid state of a layer (see StatefulLayer)
+type StateError struct {
+	Layer int
+	Name  string
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("layer %v has missing or invalid state %q", e.Layer, e.Name)
+}
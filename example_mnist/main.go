@@ -50,14 +50,17 @@ func loadTestData() ([]neural.TrainExample, []neural.TrainExample, []neural.Trai
 	return trainData, validationData, testData
 }
 
-func epocheCallback(nn neural.Evaluator, cost neural.Cost, validationData, testData []neural.TrainExample) neural.EpocheCallback {
-	return func(epoche int, dt time.Duration) {
-		_, validationErrors := neural.CalculateCorrectness(nn, cost, validationData)
+func epocheCallback(nn neural.Evaluator, cost neural.Cost, testData []neural.TrainExample) neural.EpocheCallback {
+	return func(stats neural.EpocheStats) bool {
 		_, testErrors := neural.CalculateCorrectness(nn, cost, testData)
-		if epoche == 1 {
-			fmt.Println("epoche,validation error,test error")
+		if stats.Epoche == 1 {
+			fmt.Println("epoche,learning rate,train error,validation error,test error,samples/s")
 		}
-		fmt.Printf("%v,%v,%v\n", epoche, validationErrors, testErrors)
+		fmt.Printf(
+			"%v,%v,%v,%v,%v,%.0f\n",
+			stats.Epoche, stats.LearningRate, stats.TrainErrorRate, stats.ValidationErrorRate, testErrors, stats.SamplesPerSecond,
+		)
+		return false
 	}
 }
 
@@ -79,7 +82,7 @@ func main() {
 		if err != nil {
 			log.Fatalln(err)
 		}
-		if err := neural.Load(nn, fn); err != nil {
+		if nn, err = neural.LoadNetwork(fn); err != nil {
 			log.Fatalln(err)
 		}
 	}
@@ -95,17 +98,20 @@ func main() {
 
 	cost := neural.NewLogLikelihoodCost()
 	options := neural.TrainOptions{
-		Epochs:         100,
-		MiniBatchSize:  10,
-		LearningRate:   0.4,
-		Regularization: 5,
-		TrainerFactory: neural.NewBackpropagationTrainer,
-		EpocheCallback: epocheCallback(nn, cost, validationData, testData),
-		Cost:           cost,
+		Epochs:               100,
+		MiniBatchSize:        10,
+		LearningRateSchedule: neural.NewWarmupSchedule(1, neural.NewCosineAnnealingSchedule(0.4, 0.01, 25, 3)),
+		Regularization:       5,
+		TrainerFactory:       neural.NewBackpropagationTrainer,
+		EpocheCallback:       epocheCallback(nn, cost, testData),
+		Cost:                 cost,
+		ValidationExamples:   validationData,
 	}
 
 	t0 := time.Now()
-	neural.Train(nn, trainData, options)
+	if err := neural.Train(nn, trainData, options); err != nil {
+		log.Fatalln(err)
+	}
 	dt := time.Since(t0)
 
 	fmt.Println("Training complete in", dt)
@@ -115,7 +121,7 @@ func main() {
 		if err != nil {
 			log.Fatalln(err)
 		}
-		if err := neural.Save(nn, fn); err != nil {
+		if err := neural.SaveNetwork(nn, fn); err != nil {
 			log.Fatalln(err)
 		}
 	}
@@ -0,0 +1,166 @@
+package neural
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// jsonVersion is increased with every incompatible change of the JSON schema
+const jsonVersion = 1
+
+type jsonModel struct {
+	Version int          `json:"version"`
+	Cost    *Description `json:"cost,omitempty"`
+	Layers  []jsonLayer  `json:"layers"`
+}
+
+type jsonLayer struct {
+	Inputs    int                  `json:"inputs"`
+	Neurons   int                  `json:"neurons"`
+	Layer     Description          `json:"layer"`
+	Activator Description          `json:"activator"`
+	Weights   [][]float64          `json:"weights,omitempty"`
+	Biases    []float64            `json:"biases,omitempty"`
+	State     map[string][]float64 `json:"state,omitempty"`
+}
+
+// SaveJSON writes model as JSON document, readable without Go (e.g. from Python or JavaScript).
+// Layers with weights have to implement WeightsLayer, layers with other learned values StatefulLayer.
+// Otherwise requirements are the same as of SaveModel.
+//
+// Schema of the document:
+//
+//	{
+//	  "version": 1,                              // version of the schema
+//	  "cost": {"type": "cross-entropy"},         // optional, as in Description
+//	  "layers": [
+//	    {
+//	      "inputs": 784,                         // number of inputs of the layer
+//	      "neurons": 100,                        // number of outputs of the layer
+//	      "layer": {"type": "fully-connected"},  // type of the layer and its params (see LayerNames)
+//	      "activator": {"type": "linear", "params": {"a": 1}},
+//	      "weights": [[0.1, ...], ...],          // weightsRow x weightsCol matrix (see Layer.Shapes)
+//	      "biases": [0.5, ...],                  // biasesCol values
+//	      "state": {"running_mean": [...]}       // other learned values, only for StatefulLayer
+//	    }
+//	  ]
+//	}
+//
+// Type names, params and activators are the same as in Architecture. Params without value are omitted,
+// so are weights and biases of layers without them. Rows of weights are:
+//   - fully-connected: neuron, with a weight per input
+//   - convolution: filter, with a weight per input channel and kernel position (channel, row, column)
+//   - batch-normalization: single row with gamma; biases are beta, state has running_mean and running_variance
+//
+// Numbers are written in the shortest form that parses back to the same float64.
+func SaveJSON(model Model, w io.Writer) error {
+	architecture, err := DescribeNetwork(model.Network)
+	if err != nil {
+		return err
+	}
+
+	cost, err := describeCost(model.Cost)
+	if err != nil {
+		return err
+	}
+
+	document := jsonModel{
+		Version: jsonVersion,
+		Cost:    cost,
+		Layers:  make([]jsonLayer, len(architecture.Layers), len(architecture.Layers)),
+	}
+	for l, layer := range model.Network.Layers() {
+		description := architecture.Layers[l]
+		document.Layers[l] = jsonLayer{
+			Inputs:    description.Inputs,
+			Neurons:   description.Neurons,
+			Layer:     description.Layer,
+			Activator: description.Activator,
+		}
+
+		weightsLayer, ok := layer.(WeightsLayer)
+		if weightsRow, _, biasesCol := layer.Shapes(); !ok && (weightsRow > 0 || biasesCol > 0) {
+			reason := "weights can not be read"
+			return &LayerError{Layer: l, Inputs: description.Inputs, Neurons: description.Neurons, Reason: reason}
+		}
+		if ok {
+			document.Layers[l].Weights, document.Layers[l].Biases = weightsLayer.Weights()
+		}
+
+		if stateful, ok := layer.(StatefulLayer); ok {
+			document.Layers[l].State = stateful.State()
+		}
+	}
+
+	return json.NewEncoder(w).Encode(document)
+}
+
+// LoadJSON restores model written by SaveJSON, building its network and cost.
+// Weights not matching shape of a layer give *ShapeError, missing or unexpected state *StateError.
+func LoadJSON(r io.Reader) (Model, error) {
+	var document jsonModel
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return Model{}, err
+	}
+	if document.Version != jsonVersion {
+		return Model{}, &VersionError{Version: document.Version}
+	}
+
+	architecture := Architecture{
+		Layers: make([]LayerArchitecture, len(document.Layers), len(document.Layers)),
+	}
+	for l, layer := range document.Layers {
+		architecture.Layers[l] = LayerArchitecture{
+			Inputs:    layer.Inputs,
+			Neurons:   layer.Neurons,
+			Layer:     layer.Layer,
+			Activator: layer.Activator,
+		}
+	}
+
+	nn, err := architecture.Build()
+	if err != nil {
+		return Model{}, err
+	}
+
+	for l, layer := range nn.Layers() {
+		if err := loadJSONLayer(layer, document.Layers[l]); err != nil {
+			switch e := err.(type) {
+			case *ShapeError:
+				e.Layer = l
+			case *StateError:
+				e.Layer = l
+			}
+			return Model{}, err
+		}
+	}
+
+	cost, err := buildCost(document.Cost)
+	if err != nil {
+		return Model{}, err
+	}
+	return Model{Network: nn, Cost: cost}, nil
+}
+
+// loadJSONLayer sets weights and state of a layer, after checking they match the layer
+func loadJSONLayer(layer Layer, document jsonLayer) error {
+	if err := checkShape(layer, document.Weights, document.Biases); err != nil {
+		return err
+	}
+	layer.SetWeights(document.Weights, document.Biases)
+
+	if stateful, ok := layer.(StatefulLayer); ok {
+		return stateful.SetState(document.State)
+	}
+
+	if len(document.State) > 0 {
+		names := make([]string, 0, len(document.State))
+		for name := range document.State {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return &StateError{Name: names[0]}
+	}
+	return nil
+}
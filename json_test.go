@@ -0,0 +1,104 @@
+package neural_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/mrfuxi/neural/mat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveLoadJSON(t *testing.T) {
+	input := mat.RandomVector(16)
+
+	for _, nn := range newModelTestNetworks() {
+		buffer := new(bytes.Buffer)
+		assert.NoError(t, neural.SaveJSON(neural.Model{Network: nn, Cost: neural.NewCrossEntropyCost()}, buffer))
+
+		model, err := neural.LoadJSON(buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, neural.NewCrossEntropyCost(), model.Cost)
+		assert.Equal(t, nn.Evaluate(input), model.Network.Evaluate(input))
+
+		for l, layer := range nn.Layers() {
+			weights, biases := layer.(neural.WeightsLayer).Weights()
+			loadedWeights, loadedBiases := model.Network.Layers()[l].(neural.WeightsLayer).Weights()
+			assert.Equal(t, weights, loadedWeights)
+			assert.Equal(t, biases, loadedBiases)
+		}
+	}
+}
+
+func TestSaveJSONSchema(t *testing.T) {
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 1, 1},
+		neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)),
+		neural.NewBatchNormalizationLayer(neural.NewSigmoidActivator()),
+	)
+	nn.Layers()[0].SetWeights([][]float64{{0.5, -2}}, []float64{0.125})
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.SaveJSON(neural.Model{Network: nn}, buffer))
+	assert.JSONEq(t, `{
+		"version": 1,
+		"layers": [
+			{
+				"inputs": 2,
+				"neurons": 1,
+				"layer": {"type": "fully-connected"},
+				"activator": {"type": "linear", "params": {"a": 1}},
+				"weights": [[0.5, -2]],
+				"biases": [0.125]
+			},
+			{
+				"inputs": 1,
+				"neurons": 1,
+				"layer": {"type": "batch-normalization"},
+				"activator": {"type": "sigmoid"},
+				"weights": [[1]],
+				"biases": [0],
+				"state": {"running_mean": [0], "running_variance": [1]}
+			}
+		]
+	}`, buffer.String())
+}
+
+func TestLoadJSONErrors(t *testing.T) {
+	fullyConnected := `{"inputs": 2, "neurons": 1, "layer": {"type": "fully-connected"}, "activator": {"type": "sigmoid"}, `
+	batchNormalization := `{"inputs": 1, "neurons": 1, "layer": {"type": "batch-normalization"}, "activator": {"type": "sigmoid"}, "weights": [[1]], "biases": [0], `
+
+	_, err := neural.LoadJSON(strings.NewReader(`{"version": 2, "layers": []}`))
+	assert.Equal(t, &neural.VersionError{Version: 2}, err)
+
+	_, err = neural.LoadJSON(strings.NewReader(`{"version": 1, "layers": [` + fullyConnected + `"weights": [[1, 2, 3]], "biases": [1]}]}`))
+	assert.Equal(t, &neural.ShapeError{WeightsRow: 1, WeightsCol: 2, BiasesCol: 1, SavedWeightsRow: 1, SavedWeightsCol: 3, SavedBiasesCol: 1}, err)
+
+	_, err = neural.LoadJSON(strings.NewReader(`{"version": 1, "layers": [` + fullyConnected + `"weights": [[1, 2]], "biases": [1], "state": {"b": [1], "a": [1]}}]}`))
+	assert.Equal(t, &neural.StateError{Name: "a"}, err)
+
+	_, err = neural.LoadJSON(strings.NewReader(`{"version": 1, "layers": [` + fullyConnected + `"weights": [[1, 2]], "biases": [1]}, ` +
+		batchNormalization + `"state": {"running_mean": [0]}}]}`))
+	assert.Equal(t, &neural.StateError{Layer: 1, Name: "running_variance"}, err)
+	assert.EqualError(t, err, `layer 1 has missing or invalid state "running_variance"`)
+
+	_, err = neural.LoadJSON(strings.NewReader(`{"version": 1, "layers": [`))
+	assert.Error(t, err)
+}
+
+// hiddenWeightsLayer is described, but does not expose its weights
+type hiddenWeightsLayer struct {
+	neural.Layer
+	neural.Describer
+}
+
+func TestSaveJSONHiddenWeights(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, func(inputs, neurons int) neural.Layer {
+		layer := neural.NewFullyConnectedLayer(neural.NewSigmoidActivator())(inputs, neurons)
+		return hiddenWeightsLayer{layer, layer.(neural.Describer)}
+	})
+
+	err := neural.SaveJSON(neural.Model{Network: nn}, new(bytes.Buffer))
+	assert.Equal(t, &neural.LayerError{Layer: 0, Inputs: 2, Neurons: 1, Reason: "weights can not be read"}, err)
+}
@@ -4,22 +4,81 @@ import (
 	"encoding/gob"
 	"io"
 	"math"
+	"math/rand"
 
 	"github.com/mrfuxi/neural/mat"
 )
 
 // Layer represents a single layer in nerual network
+//
+// Forward calculates potentials of the layer from input signal.
+// Backward propagates delta (error of potentials) back to the input of the layer.
+// Gradients calculates gradients of weights and biases from delta and input of the same sample.
+// Layers without weights have all Shapes equal 0 and do nothing in Gradients, SetWeights and UpdateWeights.
 type Layer interface {
 	Forward(dst, input []float64)
 	Backward(dst, delta []float64)
+	Gradients(weights [][]float64, biases []float64, delta, input []float64)
 	SetWeights(weights [][]float64, biases []float64)
 	UpdateWeights(weights [][]float64, biases []float64, regularization float64)
 	Shapes() (weightsRow, weightsCol, biasesCol int)
+	Dimensions() (inputs, outputs int)
 	Activator() Activator
 	SaverLoader
 }
 
-// LayerFactory build a Layer of certain type, used to build a network
+// Mode tells in what circumstances layer is used. Some layers (like dropout) behave differently during training.
+type Mode int
+
+const (
+	// Inference is mode of layers evaluating input signal (Evaluator.Evaluate).
+	// Layers created by LayerFactory are in this mode.
+	Inference Mode = iota
+	// Training is mode of layers used by a Trainer
+	Training
+)
+
+// SampleLayer is implemented by layers keeping state of processed sample between Forward and Backward
+// (e.g. positions of max values or dropout mask), or behaving differently depending on Mode.
+// Such layer can not be shared by concurrent trainers, so each trainer works on own copy of it
+// made with ForSample(Training). Copy has to share weights with original layer.
+type SampleLayer interface {
+	Layer
+	ForSample(mode Mode) Layer
+}
+
+// BatchLayer is implemented by layers whose output depends on whole mini-batch
+// (e.g. batch normalization using statistics of the batch). Networks with such layers are trained
+// by BatchTrainer, processing all samples of mini-batch layer by layer.
+//
+// BeginBatch gets inputs of the layer for all samples in the mini-batch before they are passed to Forward.
+// BackwardBatch propagates deltas of all samples back to the same inputs, accounting for their influence
+// on the whole batch. Backward of a single sample treats the batch as constant.
+type BatchLayer interface {
+	Layer
+	BeginBatch(inputs [][]float64)
+	BackwardBatch(dst, deltas, inputs [][]float64)
+}
+
+// WeightsLayer is implemented by layers which expose their weights and biases, in shapes given by Shapes.
+// Returned values are copies, changing them does not affect the layer (see SetWeights).
+type WeightsLayer interface {
+	Layer
+	Weights() (weights [][]float64, biases []float64)
+}
+
+// StatefulLayer is implemented by layers which learn values other than weights and biases
+// (e.g. running statistics of batch normalization), each of them stored under its name.
+// SetState returns *StateError when any of the values is missing or has wrong size.
+type StatefulLayer interface {
+	Layer
+	State() map[string][]float64
+	SetState(state map[string][]float64) error
+}
+
+// LayerFactory build a Layer of certain type, used to build a network.
+// Factory panics if the layer can not be built for given number of inputs and neurons,
+// BuildNeuralNetwork reports it as *LayerError.
 type LayerFactory func(inputs, neurons int) Layer
 
 type fullyConnectedLayer struct {
@@ -35,15 +94,21 @@ type fullyConnectedLayer struct {
 // Here it's more accruta to say it's using all input values to calculate own outputs
 // func NewFullyConnectedLayer(inputs, neurons int, activator Activator) Layer {
 func NewFullyConnectedLayer(activator Activator) LayerFactory {
+	return NewFullyConnectedLayerFrom(activator, nil)
+}
+
+// NewFullyConnectedLayerFrom works like NewFullyConnectedLayer, but initial weights are taken
+// from given source of random numbers, so they can be reproduced. Global source is used if random is nil.
+func NewFullyConnectedLayerFrom(activator Activator, random *rand.Rand) LayerFactory {
 	return func(inputs, neurons int) Layer {
 
 		weightsNorm := 1 / math.Sqrt(float64(inputs))
-		weights := mat.RandomMatrix(neurons, inputs)
+		weights := mat.RandomMatrixFrom(random, neurons, inputs)
 		mat.MulMatrixByScalar(weights, weightsNorm)
 
 		return &fullyConnectedLayer{
 			weights:   weights,
-			biases:    mat.RandomVector(neurons),
+			biases:    mat.RandomVectorFrom(random, neurons),
 			inputs:    inputs,
 			neurons:   neurons,
 			activator: activator,
@@ -55,6 +120,10 @@ func (l *fullyConnectedLayer) Shapes() (weightsRow, weightsCol, biasesCol int) {
 	return l.neurons, l.inputs, l.neurons
 }
 
+func (l *fullyConnectedLayer) Dimensions() (inputs, outputs int) {
+	return l.inputs, l.neurons
+}
+
 func (l *fullyConnectedLayer) Forward(dst, input []float64) {
 	tmp := 0.0
 	for r, row := range l.weights {
@@ -78,6 +147,11 @@ func (l *fullyConnectedLayer) Backward(dst, input []float64) {
 	}
 }
 
+func (l *fullyConnectedLayer) Gradients(weights [][]float64, biases []float64, delta, input []float64) {
+	copy(biases, delta)
+	mat.MulTransposeVector(weights, delta, input)
+}
+
 func (l *fullyConnectedLayer) SetWeights(weights [][]float64, biases []float64) {
 	for r, row := range weights {
 		copy(l.weights[r], row)
@@ -85,6 +159,10 @@ func (l *fullyConnectedLayer) SetWeights(weights [][]float64, biases []float64)
 	copy(l.biases, biases)
 }
 
+func (l *fullyConnectedLayer) Weights() (weights [][]float64, biases []float64) {
+	return copyWeights(l.weights, l.biases)
+}
+
 func (l *fullyConnectedLayer) UpdateWeights(weights [][]float64, biases []float64, regularization float64) {
 	if regularization != 1 {
 		mat.MulMatrixByScalar(l.weights, regularization)
@@ -116,12 +194,66 @@ func (l *fullyConnectedLayer) Save(w io.Writer) error {
 func (l *fullyConnectedLayer) Load(r io.Reader) error {
 	decoder := gob.NewDecoder(r)
 
-	if err := decoder.Decode(&l.biases); err != nil {
+	var biases []float64
+	if err := decoder.Decode(&biases); err != nil {
 		return err
 	}
 
-	if err := decoder.Decode(&l.weights); err != nil {
+	var weights [][]float64
+	if err := decoder.Decode(&weights); err != nil {
 		return err
 	}
+
+	if err := checkShape(l, weights, biases); err != nil {
+		return err
+	}
+	l.biases, l.weights = biases, weights
+	return nil
+}
+
+var identityActivator = NewLinearActivator(1)
+
+// weightlessLayer implements part of Layer for layers without any weights (e.g. pooling or dropout).
+// There is nothing to calculate gradients of, update, save or load.
+// Activation of such layer is an identity.
+type weightlessLayer struct{}
+
+func (l *weightlessLayer) Shapes() (weightsRow, weightsCol, biasesCol int) {
+	return 0, 0, 0
+}
+
+func (l *weightlessLayer) Gradients(weights [][]float64, biases []float64, delta, input []float64) {}
+
+func (l *weightlessLayer) SetWeights(weights [][]float64, biases []float64) {}
+
+func (l *weightlessLayer) Weights() (weights [][]float64, biases []float64) {
+	return nil, nil
+}
+
+func (l *weightlessLayer) UpdateWeights(weights [][]float64, biases []float64, regularization float64) {
+}
+
+func (l *weightlessLayer) Activator() Activator {
+	return identityActivator
+}
+
+func (l *weightlessLayer) Save(w io.Writer) error {
+	return nil
+}
+
+func (l *weightlessLayer) Load(r io.Reader) error {
 	return nil
 }
+
+func (l *fullyConnectedLayer) Describe() Description {
+	return Description{Type: "fully-connected"}
+}
+
+// copyWeights makes copies of weights and biases, so they can be given away
+func copyWeights(weights [][]float64, biases []float64) ([][]float64, []float64) {
+	weightsCopy := make([][]float64, len(weights), len(weights))
+	for r, row := range weights {
+		weightsCopy[r] = append([]float64(nil), row...)
+	}
+	return weightsCopy, append([]float64(nil), biases...)
+}
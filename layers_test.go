@@ -32,3 +32,14 @@ func TestBackwardDims(t *testing.T) {
 	layer.Backward(actualBack, []float64{0.13998155491906017})
 	assert.EqualValues(t, []float64{0.009187972010314556, 0.021909808652268586}, actualBack)
 }
+
+func TestGradients(t *testing.T) {
+	layerFactory := neural.NewFullyConnectedLayer(neural.NewStepActivator())
+	layer := layerFactory(3, 2)
+
+	weights := [][]float64{{9, 9, 9}, {9, 9, 9}}
+	biases := []float64{9, 9}
+	layer.Gradients(weights, biases, []float64{2, -1}, []float64{1, 2, 3})
+	assert.EqualValues(t, [][]float64{{2, 4, 6}, {-1, -2, -3}}, weights)
+	assert.EqualValues(t, []float64{2, -1}, biases)
+}
@@ -61,20 +61,37 @@ func MulTransposeVector(dst [][]float64, a, b []float64) [][]float64 {
 // RandomVector creates vector of given size.
 // Values are distributes using normal distribution
 func RandomVector(size int) []float64 {
+	return RandomVectorFrom(nil, size)
+}
+
+// RandomMatrix creates matrix of given size.
+// Values are distributes using normal distribution
+func RandomMatrix(rows, cols int) [][]float64 {
+	return RandomMatrixFrom(nil, rows, cols)
+}
+
+// RandomVectorFrom works like RandomVector, but takes values from given source of random numbers.
+// Global source is used if random is nil.
+func RandomVectorFrom(random *rand.Rand, size int) []float64 {
+	normFloat64 := rand.NormFloat64
+	if random != nil {
+		normFloat64 = random.NormFloat64
+	}
+
 	vector := make([]float64, size, size)
 	for col := range vector {
-		vector[col] = rand.NormFloat64()
+		vector[col] = normFloat64()
 	}
 
 	return vector
 }
 
-// RandomMatrix creates matrix of given size.
-// Values are distributes using normal distribution
-func RandomMatrix(rows, cols int) [][]float64 {
+// RandomMatrixFrom works like RandomMatrix, but takes values from given source of random numbers.
+// Global source is used if random is nil.
+func RandomMatrixFrom(random *rand.Rand, rows, cols int) [][]float64 {
 	data := make([][]float64, rows)
 	for row := range data {
-		data[row] = RandomVector(cols)
+		data[row] = RandomVectorFrom(random, cols)
 	}
 	return data
 }
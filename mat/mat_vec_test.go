@@ -1,6 +1,7 @@
 package mat_test
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/mrfuxi/neural/mat"
@@ -164,3 +165,14 @@ func TestSumMatrixPanic(t *testing.T) {
 		})
 	}
 }
+
+func TestRandomFrom(t *testing.T) {
+	vector := mat.RandomVectorFrom(rand.New(rand.NewSource(1)), 5)
+	matrix := mat.RandomMatrixFrom(rand.New(rand.NewSource(1)), 2, 3)
+
+	assert.Len(t, vector, 5)
+	assert.Len(t, matrix, 2)
+	assert.Equal(t, vector[:3], matrix[0])
+	assert.Equal(t, vector[3:], matrix[1][:2])
+	assert.Equal(t, vector, mat.RandomVectorFrom(rand.New(rand.NewSource(1)), 5))
+}
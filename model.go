@@ -0,0 +1,250 @@
+package neural
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+)
+
+// Description identifies kind of activator, cost or layer together with its parameters,
+// so the same component can be built again when a model is loaded (see LoadModel)
+type Description struct {
+	Type   string             `json:"type"`
+	Params map[string]float64 `json:"params,omitempty"`
+}
+
+// Describer is implemented by activators, costs and layers which can be saved with SaveModel.
+// Type of the description has to be registered (see RegisterActivator, RegisterCost and RegisterLayer).
+type Describer interface {
+	Describe() Description
+}
+
+// LayerArchitecture describes a single layer of a network
+type LayerArchitecture struct {
+	Inputs, Neurons int
+	Layer           Description
+	Activator       Description
+}
+
+// Architecture describes structure of a network, enough to build it again
+type Architecture struct {
+	Layers []LayerArchitecture
+}
+
+// DescribeNetwork describes architecture of the network.
+// Every layer and its activator have to implement Describer.
+func DescribeNetwork(nn Evaluator) (Architecture, error) {
+	layers := nn.Layers()
+	architecture := Architecture{
+		Layers: make([]LayerArchitecture, len(layers), len(layers)),
+	}
+
+	for l, layer := range layers {
+		inputs, neurons := layer.Dimensions()
+		layerDescriber, ok := layer.(Describer)
+		if !ok {
+			return Architecture{}, &LayerError{Layer: l, Inputs: inputs, Neurons: neurons, Reason: "layer can not be described"}
+		}
+		activatorDescriber, ok := layer.Activator().(Describer)
+		if !ok {
+			return Architecture{}, &LayerError{Layer: l, Inputs: inputs, Neurons: neurons, Reason: "activator can not be described"}
+		}
+
+		architecture.Layers[l] = LayerArchitecture{
+			Inputs:    inputs,
+			Neurons:   neurons,
+			Layer:     layerDescriber.Describe(),
+			Activator: activatorDescriber.Describe(),
+		}
+	}
+
+	return architecture, nil
+}
+
+// Build creates network of the architecture with new, random weights
+func (a Architecture) Build() (Evaluator, error) {
+	if len(a.Layers) == 0 {
+		return nil, ErrLayersCount
+	}
+
+	neurons := make([]int, len(a.Layers)+1, len(a.Layers)+1)
+	factories := make([]LayerFactory, len(a.Layers), len(a.Layers))
+	neurons[0] = a.Layers[0].Inputs
+
+	for l, layer := range a.Layers {
+		if layer.Inputs != neurons[l] {
+			reason := "inputs do not match neurons of previous layer"
+			return nil, &LayerError{Layer: l, Inputs: layer.Inputs, Neurons: layer.Neurons, Reason: reason}
+		}
+
+		activator, err := NewActivator(layer.Activator)
+		if err != nil {
+			return nil, err
+		}
+		factories[l], err = NewLayerFactory(layer.Layer, activator)
+		if err != nil {
+			return nil, err
+		}
+		neurons[l+1] = layer.Neurons
+	}
+
+	return BuildNeuralNetwork(neurons, factories...)
+}
+
+// Model is a network together with cost it's trained with
+type Model struct {
+	Network Evaluator
+	Cost    CostCostDerrivative // Optional
+}
+
+type modelHeader struct {
+	Architecture Architecture
+	Cost         *Description
+}
+
+// SaveModel persists architecture of the network, its weights and cost into a writer,
+// so the model can be loaded with LoadModel without building the network first.
+// All layers, activators and cost have to implement Describer.
+func SaveModel(model Model, w io.Writer) error {
+	architecture, err := DescribeNetwork(model.Network)
+	if err != nil {
+		return err
+	}
+
+	cost, err := describeCost(model.Cost)
+	if err != nil {
+		return err
+	}
+	header := modelHeader{Architecture: architecture, Cost: cost}
+
+	encoded := new(bytes.Buffer)
+	if err := gob.NewEncoder(encoded).Encode(header); err != nil {
+		return err
+	}
+
+	if err := writeFileHeader(w, modelFile); err != nil {
+		return err
+	}
+	if err := writeSection(w, encoded.Bytes()); err != nil {
+		return err
+	}
+	return saveLayers(model.Network, w)
+}
+
+// LoadModel restores model persisted with SaveModel, building its network and cost
+func LoadModel(r io.Reader) (Model, error) {
+	kind, err := readFileHeader(r)
+	if err != nil {
+		return Model{}, err
+	}
+	if kind != modelFile {
+		return Model{}, ErrNoArchitecture
+	}
+
+	header, err := readModelHeader(r)
+	if err != nil {
+		return Model{}, err
+	}
+
+	nn, err := header.Architecture.Build()
+	if err != nil {
+		return Model{}, err
+	}
+
+	cost, err := buildCost(header.Cost)
+	if err != nil {
+		return Model{}, err
+	}
+
+	model := Model{Network: nn, Cost: cost}
+	if err := loadLayers(nn, r); err != nil {
+		return Model{}, err
+	}
+	return model, nil
+}
+
+// readModelHeader reads section with architecture and cost of a model
+func readModelHeader(r io.Reader) (modelHeader, error) {
+	encoded, err := readSection(r, headerSection)
+	if err != nil {
+		return modelHeader{}, err
+	}
+	var header modelHeader
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&header); err != nil {
+		return modelHeader{}, err
+	}
+	return header, nil
+}
+
+// checkArchitecture reads architecture of a model and verifies that the network is built the same way
+func checkArchitecture(nn Evaluator, r io.Reader) error {
+	header, err := readModelHeader(r)
+	if err != nil {
+		return err
+	}
+	architecture, err := DescribeNetwork(nn)
+	if err != nil {
+		return err
+	}
+
+	if len(header.Architecture.Layers) != len(architecture.Layers) {
+		return ErrSavedLayersCount
+	}
+	for l, layer := range architecture.Layers {
+		saved := header.Architecture.Layers[l]
+		if !layer.equal(saved) {
+			return &ArchitectureError{Layer: l, Network: layer, Saved: saved}
+		}
+	}
+	return nil
+}
+
+func (a LayerArchitecture) equal(other LayerArchitecture) bool {
+	return a.Inputs == other.Inputs && a.Neurons == other.Neurons && a.Layer.equal(other.Layer) && a.Activator.equal(other.Activator)
+}
+
+// equal compares descriptions, treating missing and empty parameters the same
+func (d Description) equal(other Description) bool {
+	if d.Type != other.Type || len(d.Params) != len(other.Params) {
+		return false
+	}
+	for name, value := range d.Params {
+		if otherValue, ok := other.Params[name]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveNetwork persists network like SaveModel, without cost
+func SaveNetwork(nn Evaluator, w io.Writer) error {
+	return SaveModel(Model{Network: nn}, w)
+}
+
+// LoadNetwork restores network persisted with SaveNetwork (or SaveModel)
+func LoadNetwork(r io.Reader) (Evaluator, error) {
+	model, err := LoadModel(r)
+	return model.Network, err
+}
+
+// describeCost describes optional cost of a model, nil cost has no description
+func describeCost(cost CostCostDerrivative) (*Description, error) {
+	if cost == nil {
+		return nil, nil
+	}
+
+	describer, ok := cost.(Describer)
+	if !ok {
+		return nil, ErrCostNotDescribable
+	}
+	description := describer.Describe()
+	return &description, nil
+}
+
+// buildCost creates optional cost of a model
+func buildCost(description *Description) (CostCostDerrivative, error) {
+	if description == nil {
+		return nil, nil
+	}
+	return NewCost(*description)
+}
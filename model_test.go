@@ -0,0 +1,215 @@
+package neural_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/mrfuxi/neural/mat"
+	"github.com/stretchr/testify/assert"
+)
+
+func newModelTestNetworks() []neural.Evaluator {
+	convolution := neural.ConvolutionOptions{InputWidth: 4, InputHeight: 4, InputChannels: 1, KernelSize: 3, Padding: 1, Filters: 2}
+	maxPooling := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 2, Size: 2}
+	averagePooling := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 1, Size: 3, Stride: 1}
+
+	convolutional := neural.NewNeuralNetwork(
+		[]int{16, 32, 8, 8, 6, 6, 3},
+		neural.NewConvolutionalLayer(neural.NewRectActivator(), convolution),
+		neural.NewMaxPoolingLayer(maxPooling),
+		neural.NewDropoutLayer(0.3),
+		neural.NewFullyConnectedLayer(neural.NewLinearActivator(0.5)),
+		neural.NewBatchNormalizationLayer(neural.NewTanhActivator()),
+		neural.NewFullyConnectedLayer(neural.NewSoftmaxActivator()),
+	)
+	convolutional.Layers()[4].(neural.BatchLayer).BeginBatch([][]float64{{1, 2, 3, 4, 5, 6}, {0, 1, 0, 1, 0, 1}})
+
+	pooling := neural.NewNeuralNetwork(
+		[]int{16, 4, 2, 1},
+		neural.NewAveragePoolingLayer(averagePooling),
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+		neural.NewFullyConnectedLayer(neural.NewStepActivator()),
+	)
+
+	return []neural.Evaluator{convolutional, pooling}
+}
+
+// onlyReader hides all methods of reader except Read
+type onlyReader struct {
+	io.Reader
+}
+
+func TestSaveLoadNetwork(t *testing.T) {
+	input := mat.RandomVector(16)
+
+	for _, nn := range newModelTestNetworks() {
+		buffer := new(bytes.Buffer)
+		assert.NoError(t, neural.SaveNetwork(nn, buffer))
+
+		loaded, err := neural.LoadNetwork(onlyReader{buffer})
+		assert.NoError(t, err)
+		assert.Equal(t, nn.Evaluate(input), loaded.Evaluate(input))
+
+		expected, err := neural.DescribeNetwork(nn)
+		assert.NoError(t, err)
+		architecture, err := neural.DescribeNetwork(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, architecture)
+	}
+}
+
+func TestSaveLoadModel(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	costs := []neural.CostCostDerrivative{
+		neural.NewQuadraticCost(),
+		neural.NewCrossEntropyCost(),
+		neural.NewLogLikelihoodCost(),
+		nil,
+	}
+
+	for _, cost := range costs {
+		buffer := new(bytes.Buffer)
+		assert.NoError(t, neural.SaveModel(neural.Model{Network: nn, Cost: cost}, buffer))
+
+		model, err := neural.LoadModel(buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, cost, model.Cost)
+		assert.Equal(t, nn.Evaluate([]float64{1, 2}), model.Network.Evaluate([]float64{1, 2}))
+	}
+}
+
+func TestDescribeNetwork(t *testing.T) {
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 3, 3},
+		neural.NewFullyConnectedLayer(neural.NewLinearActivator(2)),
+		neural.NewDropoutLayer(0.5),
+	)
+
+	architecture, err := neural.DescribeNetwork(nn)
+	assert.NoError(t, err)
+	assert.Equal(t, neural.Architecture{Layers: []neural.LayerArchitecture{
+		{
+			Inputs:    2,
+			Neurons:   3,
+			Layer:     neural.Description{Type: "fully-connected"},
+			Activator: neural.Description{Type: "linear", Params: map[string]float64{"a": 2}},
+		},
+		{
+			Inputs:    3,
+			Neurons:   3,
+			Layer:     neural.Description{Type: "dropout", Params: map[string]float64{"probability": 0.5}},
+			Activator: neural.Description{Type: "linear", Params: map[string]float64{"a": 1}},
+		},
+	}}, architecture)
+}
+
+type undescribedLayer struct {
+	neural.Layer
+}
+
+func TestSaveModelErrors(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, func(inputs, neurons int) neural.Layer {
+		return undescribedLayer{neural.NewFullyConnectedLayer(neural.NewSigmoidActivator())(inputs, neurons)}
+	})
+	err := neural.SaveNetwork(nn, new(bytes.Buffer))
+	assert.Equal(t, &neural.LayerError{Layer: 0, Inputs: 2, Neurons: 1, Reason: "layer can not be described"}, err)
+
+	nn = neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	err = neural.SaveModel(neural.Model{Network: nn, Cost: struct{ neural.CostCostDerrivative }{}}, new(bytes.Buffer))
+	assert.Equal(t, neural.ErrCostNotDescribable, err)
+}
+
+func TestLoadModelErrors(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+
+	// Weights only, without architecture
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.Save(nn, buffer))
+	_, err := neural.LoadNetwork(buffer)
+	assert.Equal(t, neural.ErrNoArchitecture, err)
+
+	// Corrupted architecture
+	buffer.Reset()
+	assert.NoError(t, neural.SaveNetwork(nn, buffer))
+	buffer.Bytes()[40] ^= 1
+	_, err = neural.LoadNetwork(buffer)
+	assert.Equal(t, &neural.ChecksumError{Layer: -1}, err)
+
+	_, err = neural.LoadNetwork(new(bytes.Buffer))
+	assert.Equal(t, neural.ErrTruncated, err)
+}
+
+func TestLoadModelIntoNetwork(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.SaveNetwork(nn, buffer))
+
+	loaded := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	assert.NoError(t, neural.Load(loaded, buffer))
+	assert.Equal(t, nn.Evaluate([]float64{1, 2}), loaded.Evaluate([]float64{1, 2}))
+}
+
+func TestLoadModelIntoDifferentNetwork(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.SaveNetwork(nn, buffer))
+
+	// Same shape, different activator
+	other := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewTanhActivator()))
+	output := other.Evaluate([]float64{1, 2})
+	err := neural.Load(other, bytes.NewReader(buffer.Bytes()))
+	assert.Equal(t, &neural.ArchitectureError{
+		Layer: 0,
+		Network: neural.LayerArchitecture{
+			Inputs: 2, Neurons: 1,
+			Layer:     neural.Description{Type: "fully-connected"},
+			Activator: neural.Description{Type: "tanh"},
+		},
+		Saved: neural.LayerArchitecture{
+			Inputs: 2, Neurons: 1,
+			Layer:     neural.Description{Type: "fully-connected"},
+			Activator: neural.Description{Type: "sigmoid"},
+		},
+	}, err)
+	assert.EqualError(t, err, "layer 0 is fully-connected with tanh activator (2 inputs, 1 neurons), "+
+		"saved layer is fully-connected with sigmoid activator (2 inputs, 1 neurons)")
+	assert.Equal(t, output, other.Evaluate([]float64{1, 2}), "network is not modified")
+
+	// Only weights can be loaded into any network of the same shape
+	weights := new(bytes.Buffer)
+	assert.NoError(t, neural.Save(nn, weights))
+	assert.NoError(t, neural.Load(other, weights))
+}
+
+func TestArchitectureBuildErrors(t *testing.T) {
+	fullyConnected := neural.Description{Type: "fully-connected"}
+	sigmoid := neural.Description{Type: "sigmoid"}
+
+	_, err := neural.Architecture{}.Build()
+	assert.Equal(t, neural.ErrLayersCount, err)
+
+	_, err = neural.Architecture{Layers: []neural.LayerArchitecture{
+		{Inputs: 2, Neurons: 1, Layer: neural.Description{Type: "magic"}, Activator: sigmoid},
+	}}.Build()
+	assert.Equal(t, &neural.UnknownTypeError{Kind: "layer", Type: "magic"}, err)
+	assert.EqualError(t, err, `unknown layer type "magic"`)
+
+	_, err = neural.Architecture{Layers: []neural.LayerArchitecture{
+		{Inputs: 2, Neurons: 1, Layer: fullyConnected, Activator: neural.Description{Type: "magic"}},
+	}}.Build()
+	assert.Equal(t, &neural.UnknownTypeError{Kind: "activator", Type: "magic"}, err)
+
+	_, err = neural.Architecture{Layers: []neural.LayerArchitecture{
+		{Inputs: 2, Neurons: 3, Layer: fullyConnected, Activator: sigmoid},
+		{Inputs: 2, Neurons: 1, Layer: fullyConnected, Activator: sigmoid},
+	}}.Build()
+	assert.IsType(t, &neural.LayerError{}, err)
+
+	// Parameters not accepted by the layer
+	_, err = neural.Architecture{Layers: []neural.LayerArchitecture{
+		{Inputs: 2, Neurons: 2, Layer: neural.Description{Type: "dropout", Params: map[string]float64{"probability": 2}}, Activator: sigmoid},
+	}}.Build()
+	assert.IsType(t, &neural.LayerError{}, err)
+}
@@ -1,10 +1,14 @@
 package neural
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 )
 
+// Layers and training without own source of random numbers (e.g. NewFullyConnectedLayer, TrainOptions.Random)
+// use the global one. It's seeded, so they differ between runs also with Go older than 1.20,
+// where it always starts with the same seed.
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -25,20 +29,83 @@ type network struct {
 	layers []Layer
 }
 
-// NewNeuralNetwork initializes neural network structure of neurons (counts) and layer factories
+// NewNeuralNetwork initializes neural network structure of neurons (counts) and layer factories.
+// It panics if the structure is not valid, see BuildNeuralNetwork.
 func NewNeuralNetwork(neurons []int, layersFactories ...LayerFactory) Evaluator {
-	if len(neurons)-1 != len(layersFactories) {
-		panic("Neuron counts does not match layers count")
+	nn, err := BuildNeuralNetwork(neurons, layersFactories...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return nn
+}
+
+// BuildNeuralNetwork initializes neural network structure of neurons (counts) and layer factories.
+// Error is returned if counts does not match factories or any factory could not build a layer.
+func BuildNeuralNetwork(neurons []int, layersFactories ...LayerFactory) (Evaluator, error) {
+	if len(layersFactories) == 0 || len(neurons)-1 != len(layersFactories) {
+		return nil, ErrLayersCount
+	}
+	for _, count := range neurons {
+		if count <= 0 {
+			return nil, ErrNeuronsCount
+		}
 	}
 
 	layers := make([]Layer, len(layersFactories), len(layersFactories))
 	for i, factory := range layersFactories {
-		layers[i] = factory(neurons[i], neurons[i+1])
+		layer, err := buildLayer(i, factory, neurons[i], neurons[i+1])
+		if err != nil {
+			return nil, err
+		}
+		layers[i] = layer
 	}
 
 	return &network{
 		layers: layers,
+	}, nil
+}
+
+// invalidLayerError is raised by LayerFactory which can not build a layer for given number of inputs and neurons.
+// LayerFactory can not return an error, so it panics with it (see checkLayer).
+type invalidLayerError struct {
+	err error
+}
+
+func (e invalidLayerError) Error() string {
+	return e.err.Error()
+}
+
+// checkLayer makes LayerFactory fail with result of validation of the layer, if it's not valid.
+// BuildNeuralNetwork returns such failure as LayerError.
+func checkLayer(err error) {
+	if err != nil {
+		panic(invalidLayerError{err})
+	}
+}
+
+// buildLayer calls factory, turning its validation failure (see checkLayer) into LayerError.
+// Other panics of the factory are not recovered.
+func buildLayer(i int, factory LayerFactory, inputs, neurons int) (layer Layer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			invalid, ok := r.(invalidLayerError)
+			if !ok {
+				panic(r)
+			}
+			err = &LayerError{Layer: i, Inputs: inputs, Neurons: neurons, Reason: invalid.Error()}
+		}
+	}()
+
+	if factory == nil {
+		return nil, &LayerError{Layer: i, Inputs: inputs, Neurons: neurons, Reason: "factory is not set"}
+	}
+
+	layer = factory(inputs, neurons)
+	if layerInputs, layerOutputs := layer.Dimensions(); layerInputs != inputs || layerOutputs != neurons {
+		reason := fmt.Sprintf("layer has %v inputs and %v outputs", layerInputs, layerOutputs)
+		return nil, &LayerError{Layer: i, Inputs: inputs, Neurons: neurons, Reason: reason}
 	}
+	return layer, nil
 }
 
 // Evaluate calculates network answer for given input signal
@@ -46,7 +113,7 @@ func (n *network) Evaluate(input []float64) []float64 {
 	output := input
 
 	for _, layer := range n.layers {
-		n, _, _ := layer.Shapes()
+		_, n := layer.Dimensions()
 		potentials := make([]float64, n, n)
 		layer.Forward(potentials, output)
 		output = make([]float64, n, n)
@@ -319,3 +319,33 @@ func TestLearnXORSoftMaxLogLikelyhoodMomentum(t *testing.T) {
 		assert.InDeltaSlice(t, example.Output, output, 0.2)
 	}
 }
+
+func TestBuildNeuralNetworkErrors(t *testing.T) {
+	activator := neural.NewSigmoidActivator()
+	dropout := neural.NewDropoutLayer(0.5)
+
+	_, err := neural.BuildNeuralNetwork([]int{2, 3}, neural.NewFullyConnectedLayer(activator), neural.NewFullyConnectedLayer(activator))
+	assert.Equal(t, neural.ErrLayersCount, err)
+
+	_, err = neural.BuildNeuralNetwork([]int{2})
+	assert.Equal(t, neural.ErrLayersCount, err)
+
+	_, err = neural.BuildNeuralNetwork([]int{2, 0}, neural.NewFullyConnectedLayer(activator))
+	assert.Equal(t, neural.ErrNeuronsCount, err)
+
+	_, err = neural.BuildNeuralNetwork([]int{2, 3, 4}, neural.NewFullyConnectedLayer(activator), dropout)
+	assert.EqualError(t, err, "layer 1 (3 inputs, 4 neurons): dropout needs the same number of inputs and neurons, got 3 and 4")
+
+	_, err = neural.BuildNeuralNetwork([]int{2, 3}, nil)
+	assert.EqualError(t, err, "layer 0 (2 inputs, 3 neurons): factory is not set")
+
+	nn, err := neural.BuildNeuralNetwork([]int{2, 3, 3}, neural.NewFullyConnectedLayer(activator), dropout)
+	assert.NoError(t, err)
+	assert.Len(t, nn.Layers(), 2)
+
+	assert.Panics(t, func() { neural.NewNeuralNetwork([]int{2, 3, 4}, neural.NewFullyConnectedLayer(activator), dropout) })
+
+	// Only failed validation of a layer is reported as error, bugs of factories are not hidden
+	broken := func(inputs, neurons int) neural.Layer { panic("broken factory") }
+	assert.PanicsWithValue(t, "broken factory", func() { neural.BuildNeuralNetwork([]int{2, 3}, broken) })
+}
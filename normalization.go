@@ -0,0 +1,243 @@
+package neural
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	batchNormalizationMomentum = 0.9
+	batchNormalizationEpsilon  = 1e-5
+)
+
+type batchNormalizationLayer struct {
+	activator Activator
+	size      int
+	mode      Mode
+
+	gamma [][]float64 // single row, to be treated as weights
+	beta  []float64   // treated as biases
+
+	// Statistics of current mini-batch, shared with copies made for trainers
+	batchMean     []float64
+	batchVariance []float64
+
+	// Statistics used for inference, updated with every mini-batch
+	runningMean     []float64
+	runningVariance []float64
+}
+
+// NewBatchNormalizationLayer creates layer that normalizes every input to have mean 0 and variance 1 across
+// mini-batch, then scales and shifts it by learned gamma and beta. Activator is applied to the result,
+// so usually previous layer uses linear activator (NewLinearActivator(1)).
+//
+// Statistics are calculated at the beginning of every mini-batch (see BatchLayer) and their dependence
+// on every input of the batch is accounted for by BackwardBatch. Running averages of them are used in Inference mode.
+// Regularization is not applied to gamma.
+//
+// Number of inputs and neurons given when building a network has to be equal.
+func NewBatchNormalizationLayer(activator Activator) LayerFactory {
+	return func(inputs, neurons int) Layer {
+		if inputs != neurons {
+			checkLayer(fmt.Errorf("batch normalization needs the same number of inputs and neurons, got %v and %v", inputs, neurons))
+		}
+
+		l := &batchNormalizationLayer{
+			activator:       activator,
+			size:            inputs,
+			gamma:           [][]float64{make([]float64, inputs, inputs)},
+			beta:            make([]float64, inputs, inputs),
+			batchMean:       make([]float64, inputs, inputs),
+			batchVariance:   make([]float64, inputs, inputs),
+			runningMean:     make([]float64, inputs, inputs),
+			runningVariance: make([]float64, inputs, inputs),
+		}
+
+		for i := 0; i < inputs; i++ {
+			l.gamma[0][i] = 1
+			l.batchVariance[i] = 1
+			l.runningVariance[i] = 1
+		}
+
+		return l
+	}
+}
+
+func (l *batchNormalizationLayer) ForSample(mode Mode) Layer {
+	sample := *l
+	sample.mode = mode
+	return &sample
+}
+
+// BeginBatch calculates mean and variance of mini-batch and updates running averages
+func (l *batchNormalizationLayer) BeginBatch(inputs [][]float64) {
+	if len(inputs) == 0 {
+		return
+	}
+
+	n := float64(len(inputs))
+	for i := 0; i < l.size; i++ {
+		mean := 0.0
+		for _, input := range inputs {
+			mean += input[i]
+		}
+		mean /= n
+
+		variance := 0.0
+		for _, input := range inputs {
+			diff := input[i] - mean
+			variance += diff * diff
+		}
+		variance /= n
+
+		l.batchMean[i] = mean
+		l.batchVariance[i] = variance
+		l.runningMean[i] = batchNormalizationMomentum*l.runningMean[i] + (1-batchNormalizationMomentum)*mean
+		l.runningVariance[i] = batchNormalizationMomentum*l.runningVariance[i] + (1-batchNormalizationMomentum)*variance
+	}
+}
+
+func (l *batchNormalizationLayer) statistics() (mean, variance []float64) {
+	if l.mode == Training {
+		return l.batchMean, l.batchVariance
+	}
+	return l.runningMean, l.runningVariance
+}
+
+func (l *batchNormalizationLayer) Shapes() (weightsRow, weightsCol, biasesCol int) {
+	return 1, l.size, l.size
+}
+
+func (l *batchNormalizationLayer) Dimensions() (inputs, outputs int) {
+	return l.size, l.size
+}
+
+func (l *batchNormalizationLayer) Forward(dst, input []float64) {
+	mean, variance := l.statistics()
+	for i, inputValue := range input {
+		normalized := (inputValue - mean[i]) / math.Sqrt(variance[i]+batchNormalizationEpsilon)
+		dst[i] = l.gamma[0][i]*normalized + l.beta[i]
+	}
+}
+
+// BackwardBatch propagates deltas through normalization, for every input:
+// dx = gamma/sigma * (delta - mean(delta) - normalized*mean(delta*normalized))
+func (l *batchNormalizationLayer) BackwardBatch(dst, deltas, inputs [][]float64) {
+	if len(deltas) == 0 {
+		return
+	}
+
+	mean, variance := l.statistics()
+	n := float64(len(deltas))
+	for i := 0; i < l.size; i++ {
+		sigma := math.Sqrt(variance[i] + batchNormalizationEpsilon)
+
+		deltaMean, deltaNormalizedMean := 0.0, 0.0
+		for s, delta := range deltas {
+			deltaMean += delta[i]
+			deltaNormalizedMean += delta[i] * (inputs[s][i] - mean[i]) / sigma
+		}
+		deltaMean /= n
+		deltaNormalizedMean /= n
+
+		for s, delta := range deltas {
+			normalized := (inputs[s][i] - mean[i]) / sigma
+			dst[s][i] = l.gamma[0][i] / sigma * (delta[i] - deltaMean - normalized*deltaNormalizedMean)
+		}
+	}
+}
+
+// Backward treats statistics as constants, it's exact only for statistics not depending on the input (e.g. in Inference mode)
+func (l *batchNormalizationLayer) Backward(dst, delta []float64) {
+	_, variance := l.statistics()
+	for i, deltaValue := range delta {
+		dst[i] = deltaValue * l.gamma[0][i] / math.Sqrt(variance[i]+batchNormalizationEpsilon)
+	}
+}
+
+func (l *batchNormalizationLayer) Gradients(weights [][]float64, biases []float64, delta, input []float64) {
+	mean, variance := l.statistics()
+	for i, deltaValue := range delta {
+		normalized := (input[i] - mean[i]) / math.Sqrt(variance[i]+batchNormalizationEpsilon)
+		weights[0][i] = deltaValue * normalized
+		biases[i] = deltaValue
+	}
+}
+
+func (l *batchNormalizationLayer) SetWeights(weights [][]float64, biases []float64) {
+	copy(l.gamma[0], weights[0])
+	copy(l.beta, biases)
+}
+
+func (l *batchNormalizationLayer) Weights() (weights [][]float64, biases []float64) {
+	return copyWeights(l.gamma, l.beta)
+}
+
+// State returns running statistics used in Inference mode
+func (l *batchNormalizationLayer) State() map[string][]float64 {
+	return map[string][]float64{
+		"running_mean":     append([]float64(nil), l.runningMean...),
+		"running_variance": append([]float64(nil), l.runningVariance...),
+	}
+}
+
+func (l *batchNormalizationLayer) SetState(state map[string][]float64) error {
+	for _, name := range []string{"running_mean", "running_variance"} {
+		if len(state[name]) != l.size {
+			return &StateError{Name: name}
+		}
+	}
+
+	copy(l.runningMean, state["running_mean"])
+	copy(l.runningVariance, state["running_variance"])
+	return nil
+}
+
+func (l *batchNormalizationLayer) UpdateWeights(weights [][]float64, biases []float64, regularization float64) {
+	for i := range l.beta {
+		l.gamma[0][i] += weights[0][i]
+		l.beta[i] += biases[i]
+	}
+}
+
+func (l *batchNormalizationLayer) Activator() Activator {
+	return l.activator
+}
+
+func (l *batchNormalizationLayer) Save(w io.Writer) error {
+	encoder := gob.NewEncoder(w)
+
+	for _, value := range []interface{}{l.beta, l.gamma, l.runningMean, l.runningVariance} {
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *batchNormalizationLayer) Load(r io.Reader) error {
+	decoder := gob.NewDecoder(r)
+
+	var gamma [][]float64
+	var beta, runningMean, runningVariance []float64
+	for _, value := range []interface{}{&beta, &gamma, &runningMean, &runningVariance} {
+		if err := decoder.Decode(value); err != nil {
+			return err
+		}
+	}
+
+	// Running statistics have the same shape as beta
+	for _, biases := range [][]float64{beta, runningMean, runningVariance} {
+		if err := checkShape(l, gamma, biases); err != nil {
+			return err
+		}
+	}
+	l.beta, l.gamma, l.runningMean, l.runningVariance = beta, gamma, runningMean, runningVariance
+	return nil
+}
+
+func (l *batchNormalizationLayer) Describe() Description {
+	return Description{Type: "batch-normalization"}
+}
@@ -0,0 +1,187 @@
+package neural_test
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchNormalizationForward(t *testing.T) {
+	layer := neural.NewBatchNormalizationLayer(neural.NewLinearActivator(1))(2, 2)
+	batch := [][]float64{{1, 10}, {2, 10}, {3, 10}, {6, 10}}
+
+	training := layer.(neural.SampleLayer).ForSample(neural.Training)
+	training.(neural.BatchLayer).BeginBatch(batch)
+
+	outputs := make([][]float64, len(batch))
+	mean, variance := 0.0, 0.0
+	for i, input := range batch {
+		outputs[i] = make([]float64, 2)
+		training.Forward(outputs[i], input)
+		mean += outputs[i][0] / 4
+		variance += outputs[i][0] * outputs[i][0] / 4
+
+		// Constant input normalizes to 0
+		assert.InDelta(t, 0, outputs[i][1], 0.000001)
+	}
+	assert.InDelta(t, 0, mean, 0.000001)
+	assert.InDelta(t, 1, variance, 0.0001)
+
+	// Gamma and beta scale and shift normalized values
+	training.SetWeights([][]float64{{2, 1}}, []float64{1, -1})
+	output := make([]float64, 2)
+	training.Forward(output, batch[0])
+	assert.InDelta(t, 2*outputs[0][0]+1, output[0], 0.000001)
+	assert.InDelta(t, -1, output[1], 0.000001)
+
+	// Inference uses running averages, moved 10% towards the batch
+	layer.Forward(output, []float64{0.3, 1})
+	assert.InDelta(t, 2*(0.3-0.3)/math.Sqrt(0.9+0.35+0.00001)+1, output[0], 0.000001)
+	assert.InDelta(t, (1-1)/math.Sqrt(0.9+0.00001)-1, output[1], 0.000001)
+}
+
+func TestBatchNormalizationMismatchedShape(t *testing.T) {
+	assert.Panics(t, func() { neural.NewBatchNormalizationLayer(neural.NewSigmoidActivator())(4, 3) })
+}
+
+func TestBatchNormalizationSaveLoad(t *testing.T) {
+	factories := []neural.LayerFactory{
+		neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)),
+		neural.NewBatchNormalizationLayer(neural.NewSigmoidActivator()),
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+	}
+	nn := neural.NewNeuralNetwork([]int{2, 3, 3, 1}, factories...)
+	nn.Layers()[1].SetWeights([][]float64{{0.5, 2, 3}}, []float64{1, 0, -1})
+	nn.Layers()[1].(neural.BatchLayer).BeginBatch([][]float64{{1, 2, 3}, {3, 2, 1}})
+	input := []float64{0.5, -0.5}
+	expected := nn.Evaluate(input)
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.Save(nn, buffer))
+
+	newNn := neural.NewNeuralNetwork([]int{2, 3, 3, 1}, factories...)
+	assert.NoError(t, neural.Load(newNn, buffer))
+	assert.Equal(t, expected, newNn.Evaluate(input))
+}
+
+func TestLearnXORBatchNormalization(t *testing.T) {
+	testMatrix := []neural.TrainExample{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 1}, []float64{0}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+	}
+
+	random := rand.New(rand.NewSource(2))
+	linear := neural.NewLinearActivator(1)
+	activator := neural.NewSigmoidActivator()
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 8, 8, 8, 8, 1},
+		neural.NewFullyConnectedLayerFrom(linear, random),
+		neural.NewBatchNormalizationLayer(activator),
+		neural.NewFullyConnectedLayerFrom(linear, random),
+		neural.NewBatchNormalizationLayer(activator),
+		neural.NewFullyConnectedLayerFrom(activator, random),
+	)
+
+	options := neural.TrainOptions{
+		Epochs:         500,
+		MiniBatchSize:  4,
+		LearningRate:   1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewCrossEntropyCost(),
+		Random:         random,
+	}
+	assert.NoError(t, neural.Train(nn, testMatrix, options))
+
+	for _, example := range testMatrix {
+		output := nn.Evaluate(example.Input)
+		assert.InDelta(t, example.Output[0], output[0], 0.2)
+	}
+}
+
+func TestBatchNormalizationGradients(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 3, 3, 2},
+		neural.NewFullyConnectedLayerFrom(neural.NewLinearActivator(1), random),
+		neural.NewBatchNormalizationLayer(neural.NewSigmoidActivator()),
+		neural.NewFullyConnectedLayerFrom(neural.NewLinearActivator(1), random),
+	)
+	nn.Layers()[1].SetWeights([][]float64{{0.5, 2, -1}}, []float64{0.1, 0, -0.3})
+
+	batch := []neural.TrainExample{
+		{[]float64{0, 1}, []float64{1, 0}},
+		{[]float64{2, -1}, []float64{0, 1}},
+		{[]float64{0.5, 0.5}, []float64{1, 1}},
+		{[]float64{-1, 3}, []float64{0, 0}},
+	}
+	cost := neural.NewQuadraticCost()
+	trainer := neural.NewBackpropagationTrainer(nn, cost).(neural.BatchTrainer)
+
+	gradients := neural.NewWeightUpdates(nn)
+	trainer.ProcessBatch(batch, &gradients)
+
+	// Loss of the whole batch, every output depends on all samples through batch statistics
+	ignored := neural.NewWeightUpdates(nn)
+	loss := func() float64 {
+		sum := 0.0
+		for s, output := range trainer.ProcessBatch(batch, &ignored) {
+			sum += cost.Cost(output, batch[s].Output)
+		}
+		return sum
+	}
+
+	const h = 1e-6
+	scratch := neural.NewWeightUpdates(nn)
+	shift := func(l int, value *float64, by float64) {
+		*value = by
+		nn.Layers()[l].UpdateWeights(scratch.Weights[l], scratch.Biases[l], 1)
+		*value = 0
+	}
+	numerical := func(l int, value *float64) float64 {
+		shift(l, value, h)
+		plus := loss()
+		shift(l, value, -2*h)
+		minus := loss()
+		shift(l, value, h)
+		return (plus - minus) / (2 * h)
+	}
+
+	for l := range nn.Layers() {
+		for r, row := range gradients.Weights[l] {
+			for c, expected := range row {
+				scratch.Zero()
+				assert.InDelta(t, numerical(l, &scratch.Weights[l][r][c]), expected, 1e-6, "layer %v weight %v,%v", l, r, c)
+			}
+		}
+		for b, expected := range gradients.Biases[l] {
+			scratch.Zero()
+			assert.InDelta(t, numerical(l, &scratch.Biases[l][b]), expected, 1e-6, "layer %v bias %v", l, b)
+		}
+	}
+}
+
+func TestBatchNormalizationNeedsBatchTrainer(t *testing.T) {
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 2, 2},
+		neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)),
+		neural.NewBatchNormalizationLayer(neural.NewSigmoidActivator()),
+	)
+	options := neural.TrainOptions{
+		Epochs:        1,
+		MiniBatchSize: 2,
+		LearningRate:  1,
+		TrainerFactory: func(network neural.Evaluator, cost neural.CostDerivative) neural.Trainer {
+			return struct{ neural.Trainer }{neural.NewBackpropagationTrainer(network, cost)}
+		},
+		Cost: neural.NewQuadraticCost(),
+	}
+
+	examples := []neural.TrainExample{{[]float64{0, 1}, []float64{1, 0}}}
+	assert.Equal(t, neural.ErrNoBatchTrainer, neural.Train(nn, examples, options))
+}
@@ -0,0 +1,246 @@
+package neural
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Versions of ONNX written by ExportONNX
+const (
+	onnxIRVersion = 7
+	onnxOpset     = 13
+)
+
+// onnxFloat is ONNX data type of tensors with float32 values
+const onnxFloat = 1
+
+// Types of ONNX attributes
+const (
+	onnxAttributeFloat = 1
+	onnxAttributeInt   = 2
+)
+
+// onnxActivations maps types of activators to ONNX operators.
+// Linear activator does not need one, it's a part of Gemm.
+var onnxActivations = map[string]string{
+	"sigmoid": "Sigmoid",
+	"tanh":    "Tanh",
+	"rect":    "Relu",
+	"softmax": "Softmax",
+}
+
+// ExportONNX writes network as ONNX model, so it can be evaluated by other runtimes (e.g. ONNX Runtime).
+// Only fully connected layers (NewFullyConnectedLayer) with linear, sigmoid, tanh, rect or softmax
+// activators are supported, other layers give *LayerError.
+//
+// Every layer becomes Gemm operator, followed by operator of its activator.
+// Graph has single input "input" of shape [N, inputs] and output "output" of shape [N, outputs],
+// where N is number of samples. Weights are stored as float32.
+func ExportONNX(nn Evaluator, w io.Writer) error {
+	architecture, err := DescribeNetwork(nn)
+	if err != nil {
+		return err
+	}
+
+	graph := new(protoBuffer)
+	graph.string(2, "neural")
+
+	input := "input"
+	for l, layer := range nn.Layers() {
+		description := architecture.Layers[l]
+		layerError := func(reason string) error {
+			return &LayerError{Layer: l, Inputs: description.Inputs, Neurons: description.Neurons, Reason: reason}
+		}
+
+		weightsLayer, ok := layer.(WeightsLayer)
+		if description.Layer.Type != "fully-connected" || !ok {
+			return layerError(fmt.Sprintf("%v layer is not supported in ONNX", description.Layer.Type))
+		}
+
+		activation, ok := onnxActivations[description.Activator.Type]
+		alpha := 1.0
+		if description.Activator.Type == "linear" {
+			alpha = description.Activator.Params["a"]
+		} else if !ok {
+			return layerError(fmt.Sprintf("%v activator is not supported in ONNX", description.Activator.Type))
+		}
+
+		weights, biases := weightsLayer.Weights()
+		weightsName := fmt.Sprintf("layer%v_weights", l)
+		biasesName := fmt.Sprintf("layer%v_biases", l)
+		var flatWeights []float64
+		for _, row := range weights {
+			flatWeights = append(flatWeights, row...)
+		}
+		graph.message(5, onnxTensor(weightsName, []int{description.Neurons, description.Inputs}, flatWeights))
+		graph.message(5, onnxTensor(biasesName, []int{description.Neurons}, biases))
+
+		output := fmt.Sprintf("layer%v_potentials", l)
+		if activation == "" && l == len(architecture.Layers)-1 {
+			output = "output"
+		}
+
+		// Y = alpha * input * weights^T + alpha * biases, linear activator is applied together with weights
+		graph.message(1, onnxNode(fmt.Sprintf("layer%v_gemm", l), "Gemm", []string{input, weightsName, biasesName}, output,
+			onnxFloatAttribute("alpha", alpha),
+			onnxFloatAttribute("beta", alpha),
+			onnxIntAttribute("transB", 1),
+		))
+		input = output
+
+		if activation != "" {
+			output = fmt.Sprintf("layer%v_activations", l)
+			if l == len(architecture.Layers)-1 {
+				output = "output"
+			}
+
+			var attributes []*protoBuffer
+			if activation == "Softmax" {
+				attributes = append(attributes, onnxIntAttribute("axis", 1))
+			}
+			graph.message(1, onnxNode(fmt.Sprintf("layer%v_activation", l), activation, []string{input}, output, attributes...))
+			input = output
+		}
+	}
+
+	first, last := architecture.Layers[0], architecture.Layers[len(architecture.Layers)-1]
+	graph.message(11, onnxValueInfo("input", first.Inputs))
+	graph.message(12, onnxValueInfo("output", last.Neurons))
+
+	// Default domain of operators has empty name
+	opset := new(protoBuffer)
+	opset.varint(2, onnxOpset)
+
+	model := new(protoBuffer)
+	model.varint(1, onnxIRVersion)
+	model.string(2, "github.com/mrfuxi/neural")
+	model.message(7, graph)
+	model.message(8, opset)
+
+	_, err = w.Write(model.data)
+	return err
+}
+
+// onnxTensor builds TensorProto of float32 values, stored row by row
+func onnxTensor(name string, dims []int, values []float64) *protoBuffer {
+	raw := make([]byte, 4*len(values), 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(float32(value)))
+	}
+
+	tensor := new(protoBuffer)
+	for _, dim := range dims {
+		tensor.varint(1, uint64(dim))
+	}
+	tensor.varint(2, onnxFloat)
+	tensor.string(8, name)
+	tensor.bytes(9, raw)
+	return tensor
+}
+
+// onnxNode builds NodeProto of operator with single output
+func onnxNode(name, operator string, inputs []string, output string, attributes ...*protoBuffer) *protoBuffer {
+	node := new(protoBuffer)
+	for _, input := range inputs {
+		node.string(1, input)
+	}
+	node.string(2, output)
+	node.string(3, name)
+	node.string(4, operator)
+	for _, attribute := range attributes {
+		node.message(5, attribute)
+	}
+	return node
+}
+
+func onnxFloatAttribute(name string, value float64) *protoBuffer {
+	attribute := new(protoBuffer)
+	attribute.string(1, name)
+	attribute.fixed32(2, math.Float32bits(float32(value)))
+	attribute.varint(20, onnxAttributeFloat)
+	return attribute
+}
+
+func onnxIntAttribute(name string, value int) *protoBuffer {
+	attribute := new(protoBuffer)
+	attribute.string(1, name)
+	attribute.varint(3, uint64(value))
+	attribute.varint(20, onnxAttributeInt)
+	return attribute
+}
+
+// onnxValueInfo builds ValueInfoProto of float32 tensor with [N, size] shape
+func onnxValueInfo(name string, size int) *protoBuffer {
+	samples := new(protoBuffer)
+	samples.string(2, "N")
+	values := new(protoBuffer)
+	values.varint(1, uint64(size))
+
+	shape := new(protoBuffer)
+	shape.message(1, samples)
+	shape.message(1, values)
+
+	tensorType := new(protoBuffer)
+	tensorType.varint(1, onnxFloat)
+	tensorType.message(2, shape)
+
+	typeProto := new(protoBuffer)
+	typeProto.message(1, tensorType)
+
+	info := new(protoBuffer)
+	info.string(1, name)
+	info.message(2, typeProto)
+	return info
+}
+
+// protoBuffer encodes protocol buffers message, field by field
+type protoBuffer struct {
+	data []byte
+}
+
+// Wire types of protocol buffers
+const (
+	protoVarint          = 0
+	protoLengthDelimited = 2
+	protoFixed32         = 5
+)
+
+func (b *protoBuffer) key(field, wireType int) {
+	b.appendVarint(uint64(field<<3 | wireType))
+}
+
+func (b *protoBuffer) appendVarint(value uint64) {
+	for value >= 0x80 {
+		b.data = append(b.data, byte(value)|0x80)
+		value >>= 7
+	}
+	b.data = append(b.data, byte(value))
+}
+
+func (b *protoBuffer) varint(field int, value uint64) {
+	b.key(field, protoVarint)
+	b.appendVarint(value)
+}
+
+func (b *protoBuffer) fixed32(field int, value uint32) {
+	b.key(field, protoFixed32)
+	var buffer [4]byte
+	binary.LittleEndian.PutUint32(buffer[:], value)
+	b.data = append(b.data, buffer[:]...)
+}
+
+func (b *protoBuffer) bytes(field int, value []byte) {
+	b.key(field, protoLengthDelimited)
+	b.appendVarint(uint64(len(value)))
+	b.data = append(b.data, value...)
+}
+
+func (b *protoBuffer) string(field int, value string) {
+	b.bytes(field, []byte(value))
+}
+
+func (b *protoBuffer) message(field int, message *protoBuffer) {
+	b.bytes(field, message.data)
+}
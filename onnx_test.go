@@ -0,0 +1,202 @@
+package neural_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/mrfuxi/neural/mat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoMessage is decoded protocol buffers message: values of fields by field number.
+// Varint and fixed32 fields are uint64, length delimited fields are []byte.
+type protoMessage map[int][]interface{}
+
+func decodeProto(t *testing.T, data []byte) protoMessage {
+	message := make(protoMessage)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		require.True(t, n > 0, "invalid key")
+		data = data[n:]
+
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			value, n := binary.Uvarint(data)
+			require.True(t, n > 0, "invalid varint")
+			message[field] = append(message[field], value)
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			require.True(t, n > 0 && uint64(len(data)-n) >= length, "invalid length")
+			message[field] = append(message[field], data[n:n+int(length)])
+			data = data[n+int(length):]
+		case 5:
+			require.True(t, len(data) >= 4, "invalid fixed32")
+			message[field] = append(message[field], uint64(binary.LittleEndian.Uint32(data)))
+			data = data[4:]
+		default:
+			t.Fatalf("unexpected wire type %v", key&7)
+		}
+	}
+	return message
+}
+
+func (m protoMessage) message(t *testing.T, field int) protoMessage {
+	require.Len(t, m[field], 1)
+	return decodeProto(t, m[field][0].([]byte))
+}
+
+func (m protoMessage) messages(t *testing.T, field int) []protoMessage {
+	messages := make([]protoMessage, len(m[field]))
+	for i, value := range m[field] {
+		messages[i] = decodeProto(t, value.([]byte))
+	}
+	return messages
+}
+
+func (m protoMessage) strings(field int) []string {
+	var values []string
+	for _, value := range m[field] {
+		values = append(values, string(value.([]byte)))
+	}
+	return values
+}
+
+func (m protoMessage) int(field int) int {
+	return int(m[field][0].(uint64))
+}
+
+// evaluateONNXGraph evaluates decoded ONNX graph with Gemm, Sigmoid, Tanh, Relu and Softmax operators for single sample
+func evaluateONNXGraph(t *testing.T, graph protoMessage, input []float64) []float64 {
+	tensors := map[string][]float64{"input": input}
+	for _, initializer := range graph.messages(t, 5) {
+		raw := initializer[9][0].([]byte)
+		values := make([]float64, len(raw)/4)
+		for i := range values {
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:])))
+		}
+		tensors[initializer.strings(8)[0]] = values
+	}
+
+	for _, node := range graph.messages(t, 1) {
+		inputs := node.strings(1)
+		x := tensors[inputs[0]]
+		var y []float64
+
+		switch node.strings(4)[0] {
+		case "Gemm":
+			attributes := map[string]float64{}
+			for _, attribute := range node.messages(t, 5) {
+				name := attribute.strings(1)[0]
+				if attribute.int(20) == 1 {
+					attributes[name] = float64(math.Float32frombits(uint32(attribute.int(2))))
+				} else {
+					attributes[name] = float64(attribute.int(3))
+				}
+			}
+			assert.Equal(t, 1.0, attributes["transB"])
+
+			weights, biases := tensors[inputs[1]], tensors[inputs[2]]
+			y = make([]float64, len(biases))
+			for o := range y {
+				for i, value := range x {
+					y[o] += weights[o*len(x)+i] * value
+				}
+				y[o] = attributes["alpha"]*y[o] + attributes["beta"]*biases[o]
+			}
+		case "Sigmoid":
+			y = make([]float64, len(x))
+			for i, value := range x {
+				y[i] = 1 / (1 + math.Exp(-value))
+			}
+		case "Tanh":
+			y = make([]float64, len(x))
+			for i, value := range x {
+				y[i] = math.Tanh(value)
+			}
+		case "Relu":
+			y = make([]float64, len(x))
+			for i, value := range x {
+				y[i] = math.Max(value, 0)
+			}
+		case "Softmax":
+			y = make([]float64, len(x))
+			sum := 0.0
+			for i, value := range x {
+				y[i] = math.Exp(value)
+				sum += y[i]
+			}
+			mat.MulVectorByScalar(y, 1/sum)
+		default:
+			t.Fatalf("unexpected operator %v", node.strings(4))
+		}
+		tensors[node.strings(2)[0]] = y
+	}
+	return tensors["output"]
+}
+
+func TestExportONNX(t *testing.T) {
+	nn := neural.NewNeuralNetwork(
+		[]int{3, 5, 4, 4, 4, 2},
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+		neural.NewFullyConnectedLayer(neural.NewTanhActivator()),
+		neural.NewFullyConnectedLayer(neural.NewRectActivator()),
+		neural.NewFullyConnectedLayer(neural.NewLinearActivator(0.5)),
+		neural.NewFullyConnectedLayer(neural.NewSoftmaxActivator()),
+	)
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.ExportONNX(nn, buffer))
+
+	model := decodeProto(t, buffer.Bytes())
+	assert.Equal(t, 7, model.int(1))
+	assert.Equal(t, 13, model.message(t, 8).int(2))
+
+	graph := model.message(t, 7)
+	var operators []string
+	for _, node := range graph.messages(t, 1) {
+		operators = append(operators, node.strings(4)[0])
+	}
+	assert.Equal(t, []string{"Gemm", "Sigmoid", "Gemm", "Tanh", "Gemm", "Relu", "Gemm", "Gemm", "Softmax"}, operators)
+
+	// Weights and biases of the first layer
+	initializers := graph.messages(t, 5)
+	require.Len(t, initializers, 10)
+	assert.Equal(t, []string{"layer0_weights"}, initializers[0].strings(8))
+	assert.Equal(t, []interface{}{uint64(5), uint64(3)}, initializers[0][1])
+	assert.Equal(t, []interface{}{uint64(5)}, initializers[1][1])
+
+	// Input and output with dynamic number of samples
+	for field, size := range map[int]int{11: 3, 12: 2} {
+		info := graph.messages(t, field)
+		require.Len(t, info, 1)
+		dims := info[0].message(t, 2).message(t, 1).message(t, 2).messages(t, 1)
+		require.Len(t, dims, 2)
+		assert.Equal(t, []string{"N"}, dims[0].strings(2))
+		assert.Equal(t, size, dims[1].int(1))
+	}
+
+	for i := 0; i < 10; i++ {
+		input := mat.RandomVector(3)
+		assert.InDeltaSlice(t, nn.Evaluate(input), evaluateONNXGraph(t, graph, input), 1e-5)
+	}
+}
+
+func TestExportONNXNotSupported(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewStepActivator()))
+	err := neural.ExportONNX(nn, new(bytes.Buffer))
+	assert.Equal(t, &neural.LayerError{Layer: 0, Inputs: 2, Neurons: 1, Reason: "step activator is not supported in ONNX"}, err)
+
+	nn = neural.NewNeuralNetwork(
+		[]int{2, 2, 1},
+		neural.NewDropoutLayer(0.5),
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+	)
+	err = neural.ExportONNX(nn, new(bytes.Buffer))
+	assert.Equal(t, &neural.LayerError{Layer: 0, Inputs: 2, Neurons: 2, Reason: "dropout layer is not supported in ONNX"}, err)
+}
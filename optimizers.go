@@ -0,0 +1,278 @@
+package neural
+
+import (
+	"math"
+
+	"github.com/mrfuxi/neural/mat"
+)
+
+// Optimizer implements update rule used by Train.
+// Update gets gradients summed across mini-batch of given size and turns them (in place) into changes of the weights.
+// Any state kept by Optimizer should have the same shape as WeightUpdates.
+type Optimizer interface {
+	Update(gradients *WeightUpdates, batchSize int, learningRate float64)
+}
+
+// LookAheadOptimizer is an Optimizer which needs gradients evaluated at weights moved ahead of current ones
+// (e.g. Nesterov momentum). Train adds LookAhead to the weights of the network before trainers process mini-batch,
+// and takes it away before applying the update. Train may modify returned WeightUpdates.
+type LookAheadOptimizer interface {
+	Optimizer
+	LookAhead() *WeightUpdates
+}
+
+// OptimizerFactory builds Optimizer for given network. One Optimizer is created per training.
+type OptimizerFactory func(network Evaluator) Optimizer
+
+// vectors returns all vectors of WeightUpdates (biases and rows of weights) in fixed order
+func (w *WeightUpdates) vectors() [][]float64 {
+	vectors := make([][]float64, 0, len(w.Biases))
+	for l := range w.Biases {
+		vectors = append(vectors, w.Biases[l])
+		vectors = append(vectors, w.Weights[l]...)
+	}
+	return vectors
+}
+
+type momentumOptimizer struct {
+	momentum        float64
+	momentumWeights WeightUpdates
+}
+
+// NewMomentumOptimizer creates stochastic gradient descent with classical momentum:
+//
+// v = momentum * v - learningRate * gradient
+//
+// W = W + v
+//
+// With momentum equal 0 it's a plain stochastic gradient descent.
+// It's used by Train when no other Optimizer is selected.
+func NewMomentumOptimizer(momentum float64) OptimizerFactory {
+	return func(network Evaluator) Optimizer {
+		return &momentumOptimizer{
+			momentum:        momentum,
+			momentumWeights: NewWeightUpdates(network),
+		}
+	}
+}
+
+func (o *momentumOptimizer) Update(gradients *WeightUpdates, batchSize int, learningRate float64) {
+	rate := -learningRate / float64(batchSize)
+	for l := range gradients.Biases {
+		// dx = -(LR/batchSize) * W
+		mat.MulVectorByScalar(gradients.Biases[l], rate)
+		mat.MulMatrixByScalar(gradients.Weights[l], rate)
+
+		// v = momentum * v
+		mat.MulVectorByScalar(o.momentumWeights.Biases[l], o.momentum)
+		mat.MulMatrixByScalar(o.momentumWeights.Weights[l], o.momentum)
+
+		// v = v + dx
+		mat.SumVector(o.momentumWeights.Biases[l], gradients.Biases[l])
+		mat.SumMatrix(o.momentumWeights.Weights[l], gradients.Weights[l])
+
+		copy(gradients.Biases[l], o.momentumWeights.Biases[l])
+		for r, row := range o.momentumWeights.Weights[l] {
+			copy(gradients.Weights[l][r], row)
+		}
+	}
+}
+
+type nesterovOptimizer struct {
+	momentumOptimizer
+	lookAhead WeightUpdates
+}
+
+// NewNesterovOptimizer creates stochastic gradient descent with Nesterov accelerated gradient.
+// It's similar to classical momentum, but gradient is evaluated at weights after applying the momentum
+// (see LookAheadOptimizer), which corrects the velocity earlier:
+//
+// v = momentum * v - learningRate * gradient(W + momentum * v)
+//
+// W = W + v
+func NewNesterovOptimizer(momentum float64) OptimizerFactory {
+	return func(network Evaluator) Optimizer {
+		return &nesterovOptimizer{
+			momentumOptimizer: momentumOptimizer{
+				momentum:        momentum,
+				momentumWeights: NewWeightUpdates(network),
+			},
+			lookAhead: NewWeightUpdates(network),
+		}
+	}
+}
+
+func (o *nesterovOptimizer) LookAhead() *WeightUpdates {
+	for l := range o.lookAhead.Biases {
+		copy(o.lookAhead.Biases[l], o.momentumWeights.Biases[l])
+		mat.MulVectorByScalar(o.lookAhead.Biases[l], o.momentum)
+		for r, row := range o.momentumWeights.Weights[l] {
+			copy(o.lookAhead.Weights[l][r], row)
+		}
+		mat.MulMatrixByScalar(o.lookAhead.Weights[l], o.momentum)
+	}
+	return &o.lookAhead
+}
+
+type adamOptimizer struct {
+	beta1, beta2, epsilon float64
+	step                  int
+
+	m, v [][]float64 // first and second moment estimates, vectors of WeightUpdates
+}
+
+// NewAdamOptimizer creates Adam (adaptive moment estimation) optimizer.
+// It keeps exponentially decaying averages of past gradients (m) and their squares (v),
+// corrects their bias towards 0 and uses them to scale learning rate of every weight independently:
+//
+// m = beta1 * m + (1-beta1) * gradient
+//
+// v = beta2 * v + (1-beta2) * gradient^2
+//
+// W = W - learningRate * (m/(1-beta1^t)) / (sqrt(v/(1-beta2^t)) + epsilon)
+//
+// Commonly used values are beta1=0.9, beta2=0.999, epsilon=1e-8 with learning rate around 0.001.
+func NewAdamOptimizer(beta1, beta2, epsilon float64) OptimizerFactory {
+	return func(network Evaluator) Optimizer {
+		m := NewWeightUpdates(network)
+		v := NewWeightUpdates(network)
+		return &adamOptimizer{
+			beta1:   beta1,
+			beta2:   beta2,
+			epsilon: epsilon,
+			m:       m.vectors(),
+			v:       v.vectors(),
+		}
+	}
+}
+
+func (o *adamOptimizer) Update(gradients *WeightUpdates, batchSize int, learningRate float64) {
+	o.step++
+	correction1 := 1 - math.Pow(o.beta1, float64(o.step))
+	correction2 := 1 - math.Pow(o.beta2, float64(o.step))
+
+	for i, vector := range gradients.vectors() {
+		m, v := o.m[i], o.v[i]
+		for j, gradient := range vector {
+			gradient /= float64(batchSize)
+			m[j] = o.beta1*m[j] + (1-o.beta1)*gradient
+			v[j] = o.beta2*v[j] + (1-o.beta2)*gradient*gradient
+			vector[j] = -learningRate * (m[j] / correction1) / (math.Sqrt(v[j]/correction2) + o.epsilon)
+		}
+	}
+}
+
+type rmsPropOptimizer struct {
+	decay, epsilon float64
+	squares        [][]float64 // moving average of squared gradients, vectors of WeightUpdates
+}
+
+// NewRMSPropOptimizer creates RMSProp optimizer.
+// Learning rate of every weight is divided by moving average of magnitudes of its recent gradients:
+//
+// s = decay * s + (1-decay) * gradient^2
+//
+// W = W - learningRate * gradient / (sqrt(s) + epsilon)
+//
+// Commonly used values are decay=0.9, epsilon=1e-8 with learning rate around 0.001.
+func NewRMSPropOptimizer(decay, epsilon float64) OptimizerFactory {
+	return func(network Evaluator) Optimizer {
+		squares := NewWeightUpdates(network)
+		return &rmsPropOptimizer{
+			decay:   decay,
+			epsilon: epsilon,
+			squares: squares.vectors(),
+		}
+	}
+}
+
+func (o *rmsPropOptimizer) Update(gradients *WeightUpdates, batchSize int, learningRate float64) {
+	for i, vector := range gradients.vectors() {
+		squares := o.squares[i]
+		for j, gradient := range vector {
+			gradient /= float64(batchSize)
+			squares[j] = o.decay*squares[j] + (1-o.decay)*gradient*gradient
+			vector[j] = -learningRate * gradient / (math.Sqrt(squares[j]) + o.epsilon)
+		}
+	}
+}
+
+type adaGradOptimizer struct {
+	epsilon float64
+	squares [][]float64 // sum of squared gradients, vectors of WeightUpdates
+}
+
+// NewAdaGradOptimizer creates AdaGrad optimizer.
+// Learning rate of every weight is divided by square root of sum of all its past squared gradients,
+// so rarely changing weights get bigger updates:
+//
+// s = s + gradient^2
+//
+// W = W - learningRate * gradient / (sqrt(s) + epsilon)
+//
+// Commonly used epsilon is 1e-8 with learning rate around 0.01.
+func NewAdaGradOptimizer(epsilon float64) OptimizerFactory {
+	return func(network Evaluator) Optimizer {
+		squares := NewWeightUpdates(network)
+		return &adaGradOptimizer{
+			epsilon: epsilon,
+			squares: squares.vectors(),
+		}
+	}
+}
+
+func (o *adaGradOptimizer) Update(gradients *WeightUpdates, batchSize int, learningRate float64) {
+	for i, vector := range gradients.vectors() {
+		squares := o.squares[i]
+		for j, gradient := range vector {
+			gradient /= float64(batchSize)
+			squares[j] += gradient * gradient
+			vector[j] = -learningRate * gradient / (math.Sqrt(squares[j]) + o.epsilon)
+		}
+	}
+}
+
+type adaDeltaOptimizer struct {
+	rho, epsilon float64
+	squares      [][]float64 // moving average of squared gradients, vectors of WeightUpdates
+	deltas       [][]float64 // moving average of squared changes of weights, vectors of WeightUpdates
+}
+
+// NewAdaDeltaOptimizer creates AdaDelta optimizer.
+// It extends RMSProp by scaling changes of weights by moving average of their recent magnitudes,
+// so the changes have the same units as weights and learning rate is not needed:
+//
+// s = rho * s + (1-rho) * gradient^2
+//
+// dx = -sqrt(d + epsilon) / sqrt(s + epsilon) * gradient
+//
+// d = rho * d + (1-rho) * dx^2
+//
+// W = W + learningRate * dx
+//
+// Commonly used values are rho=0.95, epsilon=1e-6 with learning rate 1.
+func NewAdaDeltaOptimizer(rho, epsilon float64) OptimizerFactory {
+	return func(network Evaluator) Optimizer {
+		squares := NewWeightUpdates(network)
+		deltas := NewWeightUpdates(network)
+		return &adaDeltaOptimizer{
+			rho:     rho,
+			epsilon: epsilon,
+			squares: squares.vectors(),
+			deltas:  deltas.vectors(),
+		}
+	}
+}
+
+func (o *adaDeltaOptimizer) Update(gradients *WeightUpdates, batchSize int, learningRate float64) {
+	for i, vector := range gradients.vectors() {
+		squares, deltas := o.squares[i], o.deltas[i]
+		for j, gradient := range vector {
+			gradient /= float64(batchSize)
+			squares[j] = o.rho*squares[j] + (1-o.rho)*gradient*gradient
+			dx := -math.Sqrt(deltas[j]+o.epsilon) / math.Sqrt(squares[j]+o.epsilon) * gradient
+			deltas[j] = o.rho*deltas[j] + (1-o.rho)*dx*dx
+			vector[j] = learningRate * dx
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package neural_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOptimizerTestNetwork() (neural.Evaluator, neural.WeightUpdates) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	gradients := neural.NewWeightUpdates(nn)
+	return nn, gradients
+}
+
+func setGradients(gradients *neural.WeightUpdates, weights []float64, bias float64) {
+	copy(gradients.Weights[0][0], weights)
+	gradients.Biases[0][0] = bias
+}
+
+func TestMomentumOptimizer(t *testing.T) {
+	nn, gradients := newOptimizerTestNetwork()
+	optimizer := neural.NewMomentumOptimizer(0.5)(nn)
+
+	setGradients(&gradients, []float64{2, -4}, 8)
+	optimizer.Update(&gradients, 2, 0.5)
+	assert.Equal(t, [][]float64{{-0.5, 1}}, gradients.Weights[0])
+	assert.Equal(t, []float64{-2}, gradients.Biases[0])
+
+	// Half of previous change is added
+	setGradients(&gradients, []float64{2, -4}, 8)
+	optimizer.Update(&gradients, 2, 0.5)
+	assert.Equal(t, [][]float64{{-0.75, 1.5}}, gradients.Weights[0])
+	assert.Equal(t, []float64{-3}, gradients.Biases[0])
+}
+
+func TestAdamOptimizer(t *testing.T) {
+	nn, gradients := newOptimizerTestNetwork()
+	optimizer := neural.NewAdamOptimizer(0.9, 0.999, 1e-8)(nn)
+
+	// Thanks to bias correction first step is learning rate in direction opposite to gradient
+	setGradients(&gradients, []float64{2, -4}, 0.001)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDeltaSlice(t, []float64{-0.1, 0.1}, gradients.Weights[0][0], 0.000001)
+	assert.InDeltaSlice(t, []float64{-0.1}, gradients.Biases[0], 0.00001)
+
+	// Steps get smaller when gradients changes direction
+	setGradients(&gradients, []float64{-2, 4}, 0.001)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDelta(t, 0.0052, gradients.Weights[0][0][0], 0.0001)
+	assert.InDelta(t, -0.0052, gradients.Weights[0][0][1], 0.0001)
+	assert.InDelta(t, -0.1, gradients.Biases[0][0], 0.00001)
+}
+
+func TestLearnXORAdam(t *testing.T) {
+	learnXOR(t, 500, 0.05, neural.NewAdamOptimizer(0.9, 0.999, 1e-8))
+}
+
+func TestRMSPropOptimizer(t *testing.T) {
+	nn, gradients := newOptimizerTestNetwork()
+	optimizer := neural.NewRMSPropOptimizer(0.75, 1e-12)(nn)
+
+	setGradients(&gradients, []float64{2, -4}, 0)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDeltaSlice(t, []float64{-0.2, 0.2}, gradients.Weights[0][0], 0.000001)
+	assert.Equal(t, []float64{0}, gradients.Biases[0])
+
+	// Steps get smaller when gradients stay big
+	setGradients(&gradients, []float64{2, -4}, 0)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDeltaSlice(t, []float64{-0.1511858, 0.1511858}, gradients.Weights[0][0], 0.000001)
+}
+
+func TestAdaGradOptimizer(t *testing.T) {
+	nn, gradients := newOptimizerTestNetwork()
+	optimizer := neural.NewAdaGradOptimizer(1e-12)(nn)
+
+	setGradients(&gradients, []float64{2, -4}, 0)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDeltaSlice(t, []float64{-0.1, 0.1}, gradients.Weights[0][0], 0.000001)
+
+	// Steps decay with accumulated gradients
+	setGradients(&gradients, []float64{2, -4}, 0)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDeltaSlice(t, []float64{-0.0707107, 0.0707107}, gradients.Weights[0][0], 0.000001)
+
+	setGradients(&gradients, []float64{2, -4}, 0)
+	optimizer.Update(&gradients, 2, 0.1)
+	assert.InDeltaSlice(t, []float64{-0.0577350, 0.0577350}, gradients.Weights[0][0], 0.000001)
+}
+
+func TestAdaDeltaOptimizer(t *testing.T) {
+	nn, gradients := newOptimizerTestNetwork()
+	optimizer := neural.NewAdaDeltaOptimizer(0.5, 0.01)(nn)
+
+	setGradients(&gradients, []float64{0, 2}, 0)
+	optimizer.Update(&gradients, 1, 1)
+	// sqrt(0.01) / sqrt(2+0.01) * 2
+	assert.InDeltaSlice(t, []float64{0, -0.1410691}, gradients.Weights[0][0], 0.000001)
+
+	// Previous change makes the step bigger
+	setGradients(&gradients, []float64{0, 2}, 0)
+	optimizer.Update(&gradients, 1, 1)
+	// sqrt(0.5*0.1410811^2 + 0.01) / sqrt(3+0.01) * 2
+	assert.InDeltaSlice(t, []float64{0, -0.1628249}, gradients.Weights[0][0], 0.000001)
+}
+
+func learnXOR(t *testing.T, epochs int, learningRate float64, optimizer neural.OptimizerFactory) {
+	testMatrix := []neural.TrainExample{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 1}, []float64{0}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+	}
+
+	random := rand.New(rand.NewSource(2))
+	activator := neural.NewSigmoidActivator()
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 4, 1},
+		neural.NewFullyConnectedLayerFrom(activator, random),
+		neural.NewFullyConnectedLayerFrom(activator, random),
+	)
+
+	options := neural.TrainOptions{
+		Epochs:         epochs,
+		MiniBatchSize:  4,
+		LearningRate:   learningRate,
+		Optimizer:      optimizer,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewCrossEntropyCost(),
+		Workers:        1,
+		Random:         random,
+	}
+	assert.NoError(t, neural.Train(nn, testMatrix, options))
+
+	for _, example := range testMatrix {
+		output := nn.Evaluate(example.Input)
+		assert.InDelta(t, example.Output[0], output[0], 0.2)
+	}
+}
+
+func TestLearnXORRMSProp(t *testing.T) {
+	learnXOR(t, 500, 0.05, neural.NewRMSPropOptimizer(0.9, 1e-8))
+}
+
+func TestLearnXORAdaGrad(t *testing.T) {
+	learnXOR(t, 500, 0.5, neural.NewAdaGradOptimizer(1e-8))
+}
+
+func TestLearnXORAdaDelta(t *testing.T) {
+	learnXOR(t, 1000, 1, neural.NewAdaDeltaOptimizer(0.95, 1e-4))
+}
+
+func TestNesterovOptimizer(t *testing.T) {
+	nn, gradients := newOptimizerTestNetwork()
+	optimizer := neural.NewNesterovOptimizer(0.5)(nn).(neural.LookAheadOptimizer)
+
+	// No velocity yet, nothing to look ahead to
+	lookAhead := optimizer.LookAhead()
+	assert.Equal(t, [][]float64{{0, 0}}, lookAhead.Weights[0])
+	assert.Equal(t, []float64{0}, lookAhead.Biases[0])
+
+	setGradients(&gradients, []float64{2, -4}, 8)
+	optimizer.Update(&gradients, 2, 0.5)
+	assert.Equal(t, [][]float64{{-0.5, 1}}, gradients.Weights[0])
+	assert.Equal(t, []float64{-2}, gradients.Biases[0])
+
+	// Gradient will be evaluated where momentum leads
+	lookAhead = optimizer.LookAhead()
+	assert.Equal(t, [][]float64{{-0.25, 0.5}}, lookAhead.Weights[0])
+	assert.Equal(t, []float64{-1}, lookAhead.Biases[0])
+}
+
+func TestNesterovTrainingEvaluatesLookAhead(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{0}}, []float64{0})
+
+	// Minimize (w - 1)^2/2, gradient is w - 1
+	example := neural.TrainExample{Input: []float64{1}, Output: []float64{1}}
+	options := neural.TrainOptions{
+		Epochs:         2,
+		MiniBatchSize:  1,
+		LearningRate:   0.5,
+		Optimizer:      neural.NewNesterovOptimizer(0.5),
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+	}
+	assert.NoError(t, neural.Train(nn, []neural.TrainExample{example}, options))
+
+	// Epoch 1: v = 0.5*(1-0) = 0.5 (both weight and bias), output = 1
+	// Epoch 2: look ahead to output 1.5, v = 0.5*0.5 - 0.5*0.5 = 0, output = 1
+	assert.InDeltaSlice(t, []float64{1}, nn.Evaluate(example.Input), 0.000001)
+}
+
+func TestLearnXORNesterov(t *testing.T) {
+	learnXOR(t, 300, 3, neural.NewNesterovOptimizer(0.9))
+}
@@ -1,6 +1,12 @@
 package neural
 
-import "io"
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"hash/crc32"
+	"io"
+)
 
 // SaverLoader define persisting network and loading previously persisted data
 type SaverLoader interface {
@@ -8,23 +14,266 @@ type SaverLoader interface {
 	Load(r io.Reader) error
 }
 
+// Files written by Save and SaveModel start with magic number, format version and kind of the file.
+// Then come sections (architecture of a model and each layer), every one with its length and CRC-32 checksum.
+// Numbers are stored as big-endian uint64.
+var fileMagic = [8]byte{0x89, 'N', 'R', 'L', '\r', '\n', 0x1a, '\n'}
+
+// fileVersion is increased with every incompatible change of the format
+const fileVersion = 2
+
+// Kinds of persisted files
+const (
+	weightsFile = 1 // Weights of layers only, written by Save
+	modelFile   = 2 // Architecture followed by weights, written by SaveModel
+)
+
+// headerSection identifies section with architecture of a model in ChecksumError
+const headerSection = -1
+
 // Save persists trained network into a writer
 func Save(nn Evaluator, w io.Writer) error {
-	for _, layer := range nn.Layers() {
-		if err := layer.Save(w); err != nil {
+	if err := writeFileHeader(w, weightsFile); err != nil {
+		return err
+	}
+	return saveLayers(nn, w)
+}
+
+// Load using reader to restore previously persisted data into configured network.
+// Network has to have correct shape when loading data, otherwise *ShapeError is returned.
+// Data of a model (see SaveModel) can be loaded as well, if the network has the same architecture as the model,
+// otherwise *ArchitectureError is returned. Network is not modified when any error is returned.
+//
+// Data saved by older versions, without header and sections, gives ErrLegacyFile.
+// It's a sequence of data of layers, so it can still be loaded layer by layer with Load of every layer.
+func Load(nn Evaluator, r io.Reader) error {
+	kind, err := readFileHeader(r)
+	if err != nil {
+		return err
+	}
+
+	if kind == modelFile {
+		// Network is already built, it only has to match the architecture
+		if err := checkArchitecture(nn, r); err != nil {
+			return err
+		}
+	}
+
+	return loadLayers(nn, r)
+}
+
+func writeFileHeader(w io.Writer, kind uint64) error {
+	if _, err := w.Write(fileMagic[:]); err != nil {
+		return err
+	}
+	return writeUint64s(w, fileVersion, kind)
+}
+
+// readFileHeader checks magic number and version, and returns kind of the file
+func readFileHeader(r io.Reader) (kind uint64, err error) {
+	var magic [8]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return 0, truncated(err)
+	}
+	if magic != fileMagic {
+		if legacyFile(io.MultiReader(bytes.NewReader(magic[:]), r)) {
+			return 0, ErrLegacyFile
+		}
+		return 0, ErrBadMagic
+	}
+
+	header, err := readUint64s(r, 2)
+	if err != nil {
+		return 0, err
+	}
+	if header[0] != fileVersion {
+		return 0, &VersionError{Version: int(header[0])}
+	}
+	if header[1] != weightsFile && header[1] != modelFile {
+		return 0, ErrMalformedFile
+	}
+	return header[1], nil
+}
+
+// legacyFile checks if data was written by older version of Save, which stored only data of layers.
+// Such data starts with biases of the first (fully connected) layer encoded by gob.
+func legacyFile(r io.Reader) bool {
+	var biases []float64
+	return gob.NewDecoder(r).Decode(&biases) == nil
+}
+
+// saveLayers writes number of layers and then a section per layer, with shape of the layer and its own data
+func saveLayers(nn Evaluator, w io.Writer) error {
+	layers := nn.Layers()
+	if err := writeUint64s(w, uint64(len(layers))); err != nil {
+		return err
+	}
+
+	section := new(bytes.Buffer)
+	for _, layer := range layers {
+		section.Reset()
+
+		weightsRow, weightsCol, biasesCol := layer.Shapes()
+		if err := writeUint64s(section, uint64(weightsRow), uint64(weightsCol), uint64(biasesCol)); err != nil {
+			return err
+		}
+		if err := layer.Save(section); err != nil {
+			return err
+		}
+		if err := writeSection(w, section.Bytes()); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// Load using reader to restore previously persisted data into configured network.
-// Network has to have correct shape when loading data
-func Load(nn Evaluator, r io.Reader) error {
-	for _, layer := range nn.Layers() {
-		if err := layer.Load(r); err != nil {
+// loadLayers reads sections written by saveLayers. All sections and shapes of layers are checked
+// before data of any layer is loaded. When a layer fails to load its data, layers are restored,
+// so network is not modified.
+func loadLayers(nn Evaluator, r io.Reader) error {
+	layers := nn.Layers()
+	count, err := readUint64s(r, 1)
+	if err != nil {
+		return err
+	}
+	if count[0] != uint64(len(layers)) {
+		return ErrSavedLayersCount
+	}
+
+	sections := make([]*bytes.Reader, len(layers), len(layers))
+	for l, layer := range layers {
+		section, err := readSection(r, l)
+		if err != nil {
+			return err
+		}
+
+		sections[l] = bytes.NewReader(section)
+		saved, err := readUint64s(sections[l], 3)
+		if err != nil {
+			return ErrMalformedFile
+		}
+
+		weightsRow, weightsCol, biasesCol := layer.Shapes()
+		if saved[0] != uint64(weightsRow) || saved[1] != uint64(weightsCol) || saved[2] != uint64(biasesCol) {
+			return &ShapeError{
+				Layer:           l,
+				WeightsRow:      weightsRow,
+				WeightsCol:      weightsCol,
+				BiasesCol:       biasesCol,
+				SavedWeightsRow: int(saved[0]),
+				SavedWeightsCol: int(saved[1]),
+				SavedBiasesCol:  int(saved[2]),
+			}
+		}
+	}
+
+	backups := make([]bytes.Buffer, len(layers), len(layers))
+	for l, layer := range layers {
+		if err := layer.Save(&backups[l]); err != nil {
+			return err
+		}
+	}
+
+	for l, layer := range layers {
+		if err := layer.Load(sections[l]); err != nil {
+			// Data saved by the layers themselves loads back into them
+			for b := 0; b <= l; b++ {
+				layers[b].Load(&backups[b])
+			}
+
+			if shapeErr, ok := err.(*ShapeError); ok {
+				shapeErr.Layer = l
+			}
 			return err
 		}
 	}
 	return nil
 }
+
+// writeSection writes length of data, data and its checksum
+func writeSection(w io.Writer, data []byte) error {
+	if err := writeUint64s(w, uint64(len(data))); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return writeUint64s(w, uint64(crc32.ChecksumIEEE(data)))
+}
+
+// readSection reads data written by writeSection and verifies its checksum.
+// Memory grows with data actually read, so corrupted length does not allocate more than size of the file.
+func readSection(r io.Reader, layer int) ([]byte, error) {
+	length, err := readUint64s(r, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	data := new(bytes.Buffer)
+	if _, err := io.CopyN(data, r, int64(length[0])); err != nil {
+		return nil, truncated(err)
+	}
+
+	checksum, err := readUint64s(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	if checksum[0] != uint64(crc32.ChecksumIEEE(data.Bytes())) {
+		return nil, &ChecksumError{Layer: layer}
+	}
+	return data.Bytes(), nil
+}
+
+func writeUint64s(w io.Writer, values ...uint64) error {
+	buffer := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint64(buffer[8*i:], value)
+	}
+	_, err := w.Write(buffer)
+	return err
+}
+
+func readUint64s(r io.Reader, count int) ([]uint64, error) {
+	buffer := make([]byte, 8*count)
+	if _, err := io.ReadFull(r, buffer); err != nil {
+		return nil, truncated(err)
+	}
+
+	values := make([]uint64, count, count)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint64(buffer[8*i:])
+	}
+	return values, nil
+}
+
+// truncated replaces errors of reading past end of data with ErrTruncated
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
+
+// checkShape verifies that loaded weights and biases match shape of the layer
+func checkShape(layer Layer, weights [][]float64, biases []float64) error {
+	weightsRow, weightsCol, biasesCol := layer.Shapes()
+	savedWeightsCol := weightsCol
+	for _, row := range weights {
+		if len(row) != weightsCol {
+			savedWeightsCol = len(row)
+			break
+		}
+	}
+
+	if len(weights) == weightsRow && savedWeightsCol == weightsCol && len(biases) == biasesCol {
+		return nil
+	}
+	return &ShapeError{
+		WeightsRow:      weightsRow,
+		WeightsCol:      weightsCol,
+		BiasesCol:       biasesCol,
+		SavedWeightsRow: len(weights),
+		SavedWeightsCol: savedWeightsCol,
+		SavedBiasesCol:  len(biases),
+	}
+}
@@ -2,6 +2,8 @@ package neural_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"hash/crc32"
 	"testing"
 
 	"github.com/mrfuxi/neural"
@@ -49,3 +51,130 @@ func TestSaveLoad(t *testing.T) {
 		assert.InDelta(t, example.Output[0], output[0], 0.4999)
 	}
 }
+
+func savedNetwork(t *testing.T, nn neural.Evaluator) []byte {
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.Save(nn, buffer))
+	return buffer.Bytes()
+}
+
+func TestLoadCorrupted(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()), neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	saved := savedNetwork(t, nn)
+
+	// Last byte of data of the second layer, followed by 8 bytes of checksum
+	corrupted := append([]byte(nil), saved...)
+	corrupted[len(corrupted)-9] ^= 1
+	other := neural.NewNeuralNetwork([]int{2, 3, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()), neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	output := other.Evaluate([]float64{1, 2})
+	err := neural.Load(other, bytes.NewReader(corrupted))
+	assert.Equal(t, &neural.ChecksumError{Layer: 1}, err)
+	assert.EqualError(t, err, "checksum of layer 1 does not match")
+	assert.Equal(t, output, other.Evaluate([]float64{1, 2}), "network is not modified")
+
+	corrupted = append([]byte(nil), saved...)
+	corrupted[0] = 'N'
+	assert.Equal(t, neural.ErrBadMagic, neural.Load(nn, bytes.NewReader(corrupted)))
+
+	// Version follows magic number
+	corrupted = append([]byte(nil), saved...)
+	corrupted[15] = 7
+	assert.Equal(t, &neural.VersionError{Version: 7}, neural.Load(nn, bytes.NewReader(corrupted)))
+}
+
+func TestLoadTruncated(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()), neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	saved := savedNetwork(t, nn)
+
+	for size := 0; size < len(saved); size++ {
+		err := neural.Load(nn, bytes.NewReader(saved[:size]))
+		assert.Equal(t, neural.ErrTruncated, err, "truncated to %v bytes", size)
+	}
+}
+
+func TestLoadShapeMismatch(t *testing.T) {
+	factory := neural.NewFullyConnectedLayer(neural.NewSigmoidActivator())
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, factory, factory)
+	saved := savedNetwork(t, nn)
+
+	other := neural.NewNeuralNetwork([]int{2, 4, 1}, factory, factory)
+	output := other.Evaluate([]float64{1, 2})
+	err := neural.Load(other, bytes.NewReader(saved))
+	assert.Equal(t, &neural.ShapeError{
+		Layer:      0,
+		WeightsRow: 4, WeightsCol: 2, BiasesCol: 4,
+		SavedWeightsRow: 3, SavedWeightsCol: 2, SavedBiasesCol: 3,
+	}, err)
+	assert.EqualError(t, err, "layer 0 has 4x2 weights and 4 biases, saved layer has 3x2 weights and 3 biases")
+	assert.Equal(t, output, other.Evaluate([]float64{1, 2}), "network is not modified")
+
+	// First layer matches, so it could be loaded before the last one fails
+	other = neural.NewNeuralNetwork([]int{2, 3, 2}, factory, factory)
+	output = other.Evaluate([]float64{1, 2})
+	err = neural.Load(other, bytes.NewReader(saved))
+	assert.Equal(t, &neural.ShapeError{
+		Layer:      1,
+		WeightsRow: 2, WeightsCol: 3, BiasesCol: 2,
+		SavedWeightsRow: 1, SavedWeightsCol: 3, SavedBiasesCol: 1,
+	}, err)
+	assert.Equal(t, output, other.Evaluate([]float64{1, 2}), "network is not modified")
+
+	other = neural.NewNeuralNetwork([]int{2, 1}, factory)
+	assert.Equal(t, neural.ErrSavedLayersCount, neural.Load(other, bytes.NewReader(saved)))
+
+	// Layer checks shape of loaded data on its own
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, nn.Layers()[1].Save(buffer))
+	err = other.Layers()[0].Load(buffer)
+	assert.Equal(t, &neural.ShapeError{
+		WeightsRow: 1, WeightsCol: 2, BiasesCol: 1,
+		SavedWeightsRow: 1, SavedWeightsCol: 3, SavedBiasesCol: 1,
+	}, err)
+}
+
+func TestLoadLayerFailure(t *testing.T) {
+	factory := neural.NewFullyConnectedLayer(neural.NewSigmoidActivator())
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, factory, factory)
+	saved := savedNetwork(t, nn)
+
+	// Header with number of layers is followed by section of the first layer (its length, data and checksum).
+	// Section of the last layer is replaced with the right shape followed by data the layer can not load.
+	first := 40 + int(binary.BigEndian.Uint64(saved[32:40])) + 8
+	section := make([]byte, 24)
+	binary.BigEndian.PutUint64(section[0:], 1)
+	binary.BigEndian.PutUint64(section[8:], 3)
+	binary.BigEndian.PutUint64(section[16:], 1)
+	section = append(section, "not a layer"...)
+
+	broken := bytes.NewBuffer(append([]byte(nil), saved[:first]...))
+	assert.NoError(t, binary.Write(broken, binary.BigEndian, uint64(len(section))))
+	broken.Write(section)
+	assert.NoError(t, binary.Write(broken, binary.BigEndian, uint64(crc32.ChecksumIEEE(section))))
+
+	other := neural.NewNeuralNetwork([]int{2, 3, 1}, factory, factory)
+	output := other.Evaluate([]float64{1, 2})
+	assert.Error(t, neural.Load(other, broken))
+	assert.Equal(t, output, other.Evaluate([]float64{1, 2}), "network is not modified")
+
+	assert.NoError(t, neural.Load(other, bytes.NewReader(saved)))
+	assert.Equal(t, nn.Evaluate([]float64{1, 2}), other.Evaluate([]float64{1, 2}))
+}
+
+func TestLoadLegacyFile(t *testing.T) {
+	factory := neural.NewFullyConnectedLayer(neural.NewSigmoidActivator())
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, factory, factory)
+
+	// Older versions of Save wrote only data of every layer
+	legacy := new(bytes.Buffer)
+	for _, layer := range nn.Layers() {
+		assert.NoError(t, layer.Save(legacy))
+	}
+	assert.Equal(t, neural.ErrLegacyFile, neural.Load(nn, bytes.NewReader(legacy.Bytes())))
+
+	other := neural.NewNeuralNetwork([]int{2, 3, 1}, factory, factory)
+	reader := bytes.NewReader(legacy.Bytes())
+	for _, layer := range other.Layers() {
+		assert.NoError(t, layer.Load(reader))
+	}
+	assert.Equal(t, nn.Evaluate([]float64{1, 2}), other.Evaluate([]float64{1, 2}))
+}
@@ -0,0 +1,227 @@
+package neural
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mrfuxi/neural/mat"
+)
+
+// PoolingOptions describes shape of 2D pooling.
+//
+// Layout of input and output signals is the same as in convolution (see ConvolutionOptions).
+// Every channel is pooled independently. Windows which does not fit into input are skipped.
+type PoolingOptions struct {
+	InputWidth  int
+	InputHeight int
+	Channels    int
+
+	Size   int // Width and height of pooling window
+	Stride int // Step of the window, Size if not set
+}
+
+func (o PoolingOptions) stride() int {
+	if o.Stride <= 0 {
+		return o.Size
+	}
+	return o.Stride
+}
+
+// Validate checks if pooling layer with the options can be built for given number of inputs and neurons
+func (o PoolingOptions) Validate(inputs, neurons int) error {
+	if o.Size <= 0 || o.Channels <= 0 {
+		return errors.New("size and channels of pooling have to be positive")
+	}
+	if o.Size > o.InputWidth || o.Size > o.InputHeight {
+		return errors.New("pooling window does not fit the input")
+	}
+	if inputs != o.Inputs() {
+		return fmt.Errorf("pooling expects %v inputs, got %v", o.Inputs(), inputs)
+	}
+	if neurons != o.Outputs() {
+		return fmt.Errorf("pooling produces %v outputs, got %v", o.Outputs(), neurons)
+	}
+	return nil
+}
+
+// OutputSize calculates shape of pooling output
+func (o PoolingOptions) OutputSize() (width, height, channels int) {
+	stride := o.stride()
+	width = (o.InputWidth-o.Size)/stride + 1
+	height = (o.InputHeight-o.Size)/stride + 1
+	return width, height, o.Channels
+}
+
+// Inputs is number of input values expected by pooling layer
+func (o PoolingOptions) Inputs() int {
+	return o.InputWidth * o.InputHeight * o.Channels
+}
+
+// Outputs is number of output values produced by pooling layer
+func (o PoolingOptions) Outputs() int {
+	width, height, channels := o.OutputSize()
+	return width * height * channels
+}
+
+// poolingLayer implements everything pooling layers have in common.
+type poolingLayer struct {
+	weightlessLayer
+	PoolingOptions
+	outWidth  int
+	outHeight int
+}
+
+func newPoolingLayer(options PoolingOptions, inputs, neurons int) poolingLayer {
+	checkLayer(options.Validate(inputs, neurons))
+
+	outWidth, outHeight, _ := options.OutputSize()
+	options.Stride = options.stride()
+
+	return poolingLayer{
+		PoolingOptions: options,
+		outWidth:       outWidth,
+		outHeight:      outHeight,
+	}
+}
+
+// each calls fn for every output value with indexes of all input values in its window
+func (l *poolingLayer) each(fn func(out int, window []int)) {
+	inPlane := l.InputWidth * l.InputHeight
+	window := make([]int, l.Size*l.Size, l.Size*l.Size)
+
+	out := 0
+	for c := 0; c < l.Channels; c++ {
+		for oy := 0; oy < l.outHeight; oy++ {
+			for ox := 0; ox < l.outWidth; ox++ {
+				for wy := 0; wy < l.Size; wy++ {
+					for wx := 0; wx < l.Size; wx++ {
+						window[wy*l.Size+wx] = c*inPlane + (oy*l.Stride+wy)*l.InputWidth + ox*l.Stride + wx
+					}
+				}
+				fn(out, window)
+				out++
+			}
+		}
+	}
+}
+
+func (l *poolingLayer) Dimensions() (inputs, outputs int) {
+	return l.Inputs(), l.Outputs()
+}
+
+type maxPoolingLayer struct {
+	poolingLayer
+
+	// winners keeps index of max input for every output of last Forward, -1 before first Forward.
+	// It's set only in copies made for trainers (see SampleLayer)
+	winners []int
+}
+
+// NewMaxPoolingLayer creates layer that passes max value of every window.
+// During training delta is propagated back only to the inputs which had max values.
+// Positions of max values are known only to copies made for trainers (see SampleLayer),
+// so Backward of a layer without a sample processed by Forward propagates zero delta.
+//
+// Number of inputs and neurons given when building a network has to match options.Inputs() and options.Outputs().
+func NewMaxPoolingLayer(options PoolingOptions) LayerFactory {
+	return func(inputs, neurons int) Layer {
+		return &maxPoolingLayer{
+			poolingLayer: newPoolingLayer(options, inputs, neurons),
+		}
+	}
+}
+
+func (l *maxPoolingLayer) ForSample(mode Mode) Layer {
+	winners := make([]int, l.Outputs(), l.Outputs())
+	for i := range winners {
+		winners[i] = -1
+	}
+
+	return &maxPoolingLayer{
+		poolingLayer: l.poolingLayer,
+		winners:      winners,
+	}
+}
+
+func (l *maxPoolingLayer) Forward(dst, input []float64) {
+	l.each(func(out int, window []int) {
+		winner := window[0]
+		for _, in := range window[1:] {
+			if input[in] > input[winner] {
+				winner = in
+			}
+		}
+		dst[out] = input[winner]
+		if l.winners != nil {
+			l.winners[out] = winner
+		}
+	})
+}
+
+func (l *maxPoolingLayer) Backward(dst, delta []float64) {
+	mat.ZeroVector(dst)
+	for out, in := range l.winners {
+		if in >= 0 {
+			dst[in] += delta[out]
+		}
+	}
+}
+
+type averagePoolingLayer struct {
+	poolingLayer
+}
+
+// NewAveragePoolingLayer creates layer that passes average value of every window.
+//
+// Number of inputs and neurons given when building a network has to match options.Inputs() and options.Outputs().
+func NewAveragePoolingLayer(options PoolingOptions) LayerFactory {
+	return func(inputs, neurons int) Layer {
+		return &averagePoolingLayer{
+			poolingLayer: newPoolingLayer(options, inputs, neurons),
+		}
+	}
+}
+
+func (l *averagePoolingLayer) Forward(dst, input []float64) {
+	area := float64(l.Size * l.Size)
+	l.each(func(out int, window []int) {
+		sum := 0.0
+		for _, in := range window {
+			sum += input[in]
+		}
+		dst[out] = sum / area
+	})
+}
+
+func (l *averagePoolingLayer) Backward(dst, delta []float64) {
+	mat.ZeroVector(dst)
+
+	area := float64(l.Size * l.Size)
+	l.each(func(out int, window []int) {
+		for _, in := range window {
+			dst[in] += delta[out] / area
+		}
+	})
+}
+
+// describe describes pooling layer of given type
+func (l *poolingLayer) describe(kind string) Description {
+	return Description{
+		Type: kind,
+		Params: map[string]float64{
+			"input_width":  float64(l.InputWidth),
+			"input_height": float64(l.InputHeight),
+			"channels":     float64(l.Channels),
+			"size":         float64(l.Size),
+			"stride":       float64(l.stride()),
+		},
+	}
+}
+
+func (l *maxPoolingLayer) Describe() Description {
+	return l.describe("max-pooling")
+}
+
+func (l *averagePoolingLayer) Describe() Description {
+	return l.describe("average-pooling")
+}
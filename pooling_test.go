@@ -0,0 +1,185 @@
+package neural_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+var poolingInput = []float64{
+	1, 2, 3, 4,
+	5, 6, 7, 8,
+	-1, -2, 0, 0,
+	-3, -9, 0, 1,
+}
+
+func TestPoolingOutputSize(t *testing.T) {
+	testMatrix := []struct {
+		options         neural.PoolingOptions
+		width, height   int
+		inputs, outputs int
+	}{
+		{neural.PoolingOptions{InputWidth: 24, InputHeight: 24, Channels: 6, Size: 2}, 12, 12, 3456, 864},
+		{neural.PoolingOptions{InputWidth: 5, InputHeight: 5, Channels: 1, Size: 2}, 2, 2, 25, 4},
+		{neural.PoolingOptions{InputWidth: 5, InputHeight: 4, Channels: 2, Size: 3, Stride: 1}, 3, 2, 40, 12},
+	}
+
+	for _, example := range testMatrix {
+		width, height, channels := example.options.OutputSize()
+		assert.Equal(t, example.width, width)
+		assert.Equal(t, example.height, height)
+		assert.Equal(t, example.options.Channels, channels)
+		assert.Equal(t, example.inputs, example.options.Inputs())
+		assert.Equal(t, example.outputs, example.options.Outputs())
+	}
+}
+
+func TestPoolingValidate(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 5, InputHeight: 5, Channels: 1, Size: 2}
+	assert.NoError(t, options.Validate(25, 4))
+	assert.EqualError(t, options.Validate(24, 4), "pooling expects 25 inputs, got 24")
+	assert.EqualError(t, options.Validate(25, 5), "pooling produces 4 outputs, got 5")
+
+	options.Size = 6
+	assert.EqualError(t, options.Validate(25, 0), "pooling window does not fit the input")
+
+	options.Size = 0
+	assert.EqualError(t, options.Validate(25, 0), "size and channels of pooling have to be positive")
+
+	_, err := neural.BuildNeuralNetwork([]int{24, 4}, neural.NewMaxPoolingLayer(neural.PoolingOptions{InputWidth: 5, InputHeight: 5, Channels: 1, Size: 2}))
+	assert.EqualError(t, err, "layer 0 (24 inputs, 4 neurons): pooling expects 25 inputs, got 24")
+}
+
+func TestMaxPoolingForward(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 1, Size: 2}
+	layer := neural.NewMaxPoolingLayer(options)(16, 4)
+
+	output := make([]float64, 4)
+	layer.Forward(output, poolingInput)
+	assert.Equal(t, []float64{6, 8, -1, 1}, output)
+}
+
+func TestMaxPoolingForwardOverlapping(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 1, Size: 3, Stride: 1}
+	layer := neural.NewMaxPoolingLayer(options)(16, 4)
+
+	output := make([]float64, 4)
+	layer.Forward(output, poolingInput)
+	assert.Equal(t, []float64{7, 8, 7, 8}, output)
+}
+
+func TestAveragePoolingForward(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 1, Size: 2}
+	layer := neural.NewAveragePoolingLayer(options)(16, 4)
+
+	output := make([]float64, 4)
+	layer.Forward(output, poolingInput)
+	assert.Equal(t, []float64{3.5, 5.5, -3.75, 0.25}, output)
+}
+
+func TestAveragePoolingBackward(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 4, InputHeight: 2, Channels: 1, Size: 2}
+	layer := neural.NewAveragePoolingLayer(options)(8, 2)
+
+	back := make([]float64, 8)
+	layer.Backward(back, []float64{4, -8})
+	assert.Equal(t, []float64{1, 1, -2, -2, 1, 1, -2, -2}, back)
+}
+
+func TestMaxPoolingBackward(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 1, Size: 2}
+	linear := neural.NewLinearActivator(1)
+	nn := neural.NewNeuralNetwork(
+		[]int{16, 16, 4},
+		neural.NewFullyConnectedLayer(linear),
+		neural.NewMaxPoolingLayer(options),
+	)
+
+	// Identity, so max pooling sees the input directly
+	weights := make([][]float64, 16)
+	for i := range weights {
+		weights[i] = make([]float64, 16)
+		weights[i][i] = 1
+	}
+	nn.Layers()[0].SetWeights(weights, make([]float64, 16))
+
+	// Max pooling does not know winners without a sample, nothing is propagated
+	dst := []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	nn.Layers()[1].Backward(dst, []float64{1, 2, 3, 4})
+	assert.Equal(t, make([]float64, 16), dst)
+	sample := nn.Layers()[1].(neural.SampleLayer).ForSample(neural.Training)
+	sample.Backward(dst, []float64{1, 2, 3, 4})
+	assert.Equal(t, make([]float64, 16), dst)
+
+	trainer := neural.NewBackpropagationTrainer(nn, neural.NewQuadraticCost())
+	weightUpdates := neural.NewWeightUpdates(nn)
+	trainer.Process(neural.TrainExample{Input: poolingInput, Output: []float64{0, 0, 0, 0}}, &weightUpdates)
+
+	// Delta of first layer is non zero only for winning positions
+	assert.Equal(t, []float64{
+		0, 0, 0, 0,
+		0, 6, 0, 8,
+		-1, 0, 0, 0,
+		0, 0, 0, 1,
+	}, weightUpdates.Biases[0])
+
+	// Pooling layer has nothing to update
+	assert.Len(t, weightUpdates.Biases[1], 0)
+	assert.Len(t, weightUpdates.Weights[1], 0)
+}
+
+func TestConvolutionPoolingLearn(t *testing.T) {
+	// Detect vertical (1) and horizontal (0) lines on 4x4 image
+	testMatrix := []neural.TrainExample{
+		{[]float64{1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0}, []float64{1}},
+		{[]float64{0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0}, []float64{1}},
+		{[]float64{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}, []float64{1}},
+		{[]float64{1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, []float64{0}},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 0, 0, 0, 0}, []float64{0}},
+		{[]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1}, []float64{0}},
+	}
+
+	activator := neural.NewSigmoidActivator()
+	convolution := neural.ConvolutionOptions{InputWidth: 4, InputHeight: 4, InputChannels: 1, KernelSize: 3, Padding: 1, Filters: 2}
+	pooling := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 2, Size: 2}
+	nn := neural.NewNeuralNetwork(
+		[]int{convolution.Inputs(), convolution.Outputs(), pooling.Outputs(), 1},
+		neural.NewConvolutionalLayer(activator, convolution),
+		neural.NewMaxPoolingLayer(pooling),
+		neural.NewFullyConnectedLayer(activator),
+	)
+
+	trainOptions := neural.TrainOptions{
+		Epochs:         1000,
+		MiniBatchSize:  3,
+		LearningRate:   1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewCrossEntropyCost(),
+	}
+	neural.Train(nn, testMatrix, trainOptions)
+
+	for _, example := range testMatrix {
+		output := nn.Evaluate(example.Input)
+		assert.InDelta(t, example.Output[0], output[0], 0.2)
+	}
+}
+
+func TestPoolingSaveLoad(t *testing.T) {
+	options := neural.PoolingOptions{InputWidth: 4, InputHeight: 4, Channels: 1, Size: 2}
+	factories := []neural.LayerFactory{
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+		neural.NewAveragePoolingLayer(options),
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+	}
+	nn := neural.NewNeuralNetwork([]int{16, 16, 4, 2}, factories...)
+	expected := nn.Evaluate(poolingInput)
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.Save(nn, buffer))
+
+	newNn := neural.NewNeuralNetwork([]int{16, 16, 4, 2}, factories...)
+	assert.NoError(t, neural.Load(newNn, buffer))
+	assert.Equal(t, expected, newNn.Evaluate(poolingInput))
+}
@@ -0,0 +1,280 @@
+package neural
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// ActivatorBuilder creates Activator with given parameters
+type ActivatorBuilder func(params map[string]float64) (Activator, error)
+
+// CostBuilder creates cost with given parameters
+type CostBuilder func(params map[string]float64) (CostCostDerrivative, error)
+
+// LayerBuilder creates LayerFactory with given parameters and activator.
+// Layers which do not use activator (e.g. pooling) ignore it.
+type LayerBuilder func(params map[string]float64, activator Activator) (LayerFactory, error)
+
+// registry maps names of activators, costs and layers to their builders
+var registry = struct {
+	sync.RWMutex
+	activators map[string]ActivatorBuilder
+	costs      map[string]CostBuilder
+	layers     map[string]LayerBuilder
+}{
+	activators: make(map[string]ActivatorBuilder),
+	costs:      make(map[string]CostBuilder),
+	layers:     make(map[string]LayerBuilder),
+}
+
+// RegisterActivator makes activator available under given name (see NewActivator).
+// It panics if the name is already registered.
+func RegisterActivator(name string, builder ActivatorBuilder) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.activators[name]; ok {
+		panic(fmt.Sprintf("Activator %q is already registered", name))
+	}
+	registry.activators[name] = builder
+}
+
+// RegisterCost makes cost available under given name (see NewCost).
+// It panics if the name is already registered.
+func RegisterCost(name string, builder CostBuilder) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.costs[name]; ok {
+		panic(fmt.Sprintf("Cost %q is already registered", name))
+	}
+	registry.costs[name] = builder
+}
+
+// RegisterLayer makes layer available under given name (see NewLayerFactory).
+// It panics if the name is already registered.
+func RegisterLayer(name string, builder LayerBuilder) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.layers[name]; ok {
+		panic(fmt.Sprintf("Layer %q is already registered", name))
+	}
+	registry.layers[name] = builder
+}
+
+// NewActivator creates registered activator of described type
+func NewActivator(description Description) (Activator, error) {
+	registry.RLock()
+	builder, ok := registry.activators[description.Type]
+	registry.RUnlock()
+
+	if !ok {
+		return nil, &UnknownTypeError{Kind: "activator", Type: description.Type}
+	}
+
+	built, err := builder(description.Params)
+	return built, paramError(err, "activator", description.Type)
+}
+
+// NewCost creates registered cost of described type
+func NewCost(description Description) (CostCostDerrivative, error) {
+	registry.RLock()
+	builder, ok := registry.costs[description.Type]
+	registry.RUnlock()
+
+	if !ok {
+		return nil, &UnknownTypeError{Kind: "cost", Type: description.Type}
+	}
+
+	built, err := builder(description.Params)
+	return built, paramError(err, "cost", description.Type)
+}
+
+// NewLayerFactory creates LayerFactory of registered layer of described type
+func NewLayerFactory(description Description, activator Activator) (LayerFactory, error) {
+	registry.RLock()
+	builder, ok := registry.layers[description.Type]
+	registry.RUnlock()
+
+	if !ok {
+		return nil, &UnknownTypeError{Kind: "layer", Type: description.Type}
+	}
+
+	built, err := builder(description.Params, activator)
+	return built, paramError(err, "layer", description.Type)
+}
+
+// ActivatorNames lists names of all registered activators, sorted
+func ActivatorNames() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.activators))
+	for name := range registry.activators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CostNames lists names of all registered costs, sorted
+func CostNames() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.costs))
+	for name := range registry.costs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LayerNames lists names of all registered layers, sorted
+func LayerNames() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.layers))
+	for name := range registry.layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// paramError completes ParamError returned by a builder with kind and type of built component
+func paramError(err error, kind, name string) error {
+	if e, ok := err.(*ParamError); ok && e.Type == "" {
+		e.Kind = kind
+		e.Type = name
+	}
+	return err
+}
+
+// param gets required parameter
+func param(params map[string]float64, name string) (float64, error) {
+	value, ok := params[name]
+	if !ok {
+		return 0, &ParamError{Param: name}
+	}
+	return value, nil
+}
+
+// intParams gets required integer parameters, and optional ones (0 if missing).
+// Parameters with fraction or infinite give ParamError.
+func intParams(params map[string]float64, required []string, optional []string) (map[string]int, error) {
+	values := make(map[string]int, len(required)+len(optional))
+	for _, name := range required {
+		if _, err := param(params, name); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range append(append([]string(nil), required...), optional...) {
+		value := params[name]
+		if value != math.Trunc(value) || math.IsInf(value, 0) {
+			return nil, &ParamError{Param: name, Reason: fmt.Sprintf("%v is not an integer", value)}
+		}
+		values[name] = int(value)
+	}
+	return values, nil
+}
+
+// constantActivator wraps constructor of activator without parameters
+func constantActivator(constructor func() Activator) ActivatorBuilder {
+	return func(params map[string]float64) (Activator, error) {
+		return constructor(), nil
+	}
+}
+
+// constantCost wraps constructor of cost without parameters
+func constantCost(constructor func() CostCostDerrivative) CostBuilder {
+	return func(params map[string]float64) (CostCostDerrivative, error) {
+		return constructor(), nil
+	}
+}
+
+func init() {
+	RegisterActivator("linear", func(params map[string]float64) (Activator, error) {
+		a, err := param(params, "a")
+		if err != nil {
+			return nil, err
+		}
+		return NewLinearActivator(a), nil
+	})
+	RegisterActivator("sigmoid", constantActivator(NewSigmoidActivator))
+	RegisterActivator("step", constantActivator(NewStepActivator))
+	RegisterActivator("softmax", constantActivator(NewSoftmaxActivator))
+	RegisterActivator("tanh", constantActivator(NewTanhActivator))
+	RegisterActivator("rect", constantActivator(NewRectActivator))
+
+	RegisterCost("quadratic", constantCost(NewQuadraticCost))
+	RegisterCost("cross-entropy", constantCost(NewCrossEntropyCost))
+	RegisterCost("log-likelihood", constantCost(NewLogLikelihoodCost))
+
+	RegisterLayer("fully-connected", func(params map[string]float64, activator Activator) (LayerFactory, error) {
+		return NewFullyConnectedLayer(activator), nil
+	})
+	RegisterLayer("convolution", func(params map[string]float64, activator Activator) (LayerFactory, error) {
+		values, err := intParams(
+			params,
+			[]string{"input_width", "input_height", "input_channels", "kernel_size", "filters"},
+			[]string{"stride", "padding"},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewConvolutionalLayer(activator, ConvolutionOptions{
+			InputWidth:    values["input_width"],
+			InputHeight:   values["input_height"],
+			InputChannels: values["input_channels"],
+			KernelSize:    values["kernel_size"],
+			Stride:        values["stride"],
+			Padding:       values["padding"],
+			Filters:       values["filters"],
+		}), nil
+	})
+	RegisterLayer("max-pooling", func(params map[string]float64, activator Activator) (LayerFactory, error) {
+		options, err := poolingOptions(params)
+		if err != nil {
+			return nil, err
+		}
+		return NewMaxPoolingLayer(options), nil
+	})
+	RegisterLayer("average-pooling", func(params map[string]float64, activator Activator) (LayerFactory, error) {
+		options, err := poolingOptions(params)
+		if err != nil {
+			return nil, err
+		}
+		return NewAveragePoolingLayer(options), nil
+	})
+	RegisterLayer("dropout", func(params map[string]float64, activator Activator) (LayerFactory, error) {
+		probability, err := param(params, "probability")
+		if err != nil {
+			return nil, err
+		}
+		return NewDropoutLayer(probability), nil
+	})
+	RegisterLayer("batch-normalization", func(params map[string]float64, activator Activator) (LayerFactory, error) {
+		return NewBatchNormalizationLayer(activator), nil
+	})
+}
+
+func poolingOptions(params map[string]float64) (PoolingOptions, error) {
+	values, err := intParams(params, []string{"input_width", "input_height", "channels", "size"}, []string{"stride"})
+	if err != nil {
+		return PoolingOptions{}, err
+	}
+
+	return PoolingOptions{
+		InputWidth:  values["input_width"],
+		InputHeight: values["input_height"],
+		Channels:    values["channels"],
+		Size:        values["size"],
+		Stride:      values["stride"],
+	}, nil
+}
@@ -0,0 +1,111 @@
+package neural_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisteredNames(t *testing.T) {
+	assert.Subset(t, neural.ActivatorNames(), []string{"linear", "rect", "sigmoid", "softmax", "step", "tanh"})
+	assert.Subset(t, neural.CostNames(), []string{"cross-entropy", "log-likelihood", "quadratic"})
+	assert.Subset(t, neural.LayerNames(), []string{
+		"average-pooling", "batch-normalization", "convolution", "dropout", "fully-connected", "max-pooling",
+	})
+}
+
+func TestNewActivator(t *testing.T) {
+	activator, err := neural.NewActivator(neural.Description{Type: "linear", Params: map[string]float64{"a": 2}})
+	assert.NoError(t, err)
+
+	output := make([]float64, 2)
+	activator.Activation(output, []float64{1, -3})
+	assert.Equal(t, []float64{2, -6}, output)
+
+	_, err = neural.NewActivator(neural.Description{Type: "linear"})
+	assert.Equal(t, &neural.ParamError{Kind: "activator", Type: "linear", Param: "a"}, err)
+	assert.EqualError(t, err, `activator "linear" needs parameter "a"`)
+
+	_, err = neural.NewActivator(neural.Description{Type: "magic"})
+	assert.Equal(t, &neural.UnknownTypeError{Kind: "activator", Type: "magic"}, err)
+}
+
+func TestNewCost(t *testing.T) {
+	cost, err := neural.NewCost(neural.Description{Type: "cross-entropy"})
+	assert.NoError(t, err)
+	assert.Equal(t, neural.NewCrossEntropyCost(), cost)
+
+	_, err = neural.NewCost(neural.Description{Type: "magic"})
+	assert.Equal(t, &neural.UnknownTypeError{Kind: "cost", Type: "magic"}, err)
+}
+
+func TestNewLayerFactory(t *testing.T) {
+	params := map[string]float64{"input_width": 4, "input_height": 4, "channels": 1}
+	_, err := neural.NewLayerFactory(neural.Description{Type: "max-pooling", Params: params}, nil)
+	assert.Equal(t, &neural.ParamError{Kind: "layer", Type: "max-pooling", Param: "size"}, err)
+
+	params["size"] = 2.5
+	_, err = neural.NewLayerFactory(neural.Description{Type: "max-pooling", Params: params}, nil)
+	assert.Equal(t, &neural.ParamError{Kind: "layer", Type: "max-pooling", Param: "size", Reason: "2.5 is not an integer"}, err)
+	assert.EqualError(t, err, `layer "max-pooling" has invalid parameter "size": 2.5 is not an integer`)
+
+	params["stride"] = 0.5
+	params["size"] = 2
+	_, err = neural.NewLayerFactory(neural.Description{Type: "max-pooling", Params: params}, nil)
+	assert.Equal(t, &neural.ParamError{Kind: "layer", Type: "max-pooling", Param: "stride", Reason: "0.5 is not an integer"}, err)
+	delete(params, "stride")
+
+	params["size"] = 2
+	factory, err := neural.NewLayerFactory(neural.Description{Type: "max-pooling", Params: params}, nil)
+	assert.NoError(t, err)
+
+	output := make([]float64, 4)
+	factory(16, 4).Forward(output, poolingInput)
+	assert.Equal(t, []float64{6, 8, -1, 1}, output)
+}
+
+// doubleActivator is custom activator registered by tests
+type doubleActivator struct{}
+
+func (d doubleActivator) Activation(dst, potentials []float64) {
+	for i, potential := range potentials {
+		dst[i] = 2 * potential
+	}
+}
+
+func (d doubleActivator) Derivative(dst, potentials []float64) {
+	for i := range potentials {
+		dst[i] = 2
+	}
+}
+
+func (d doubleActivator) Describe() neural.Description {
+	return neural.Description{Type: "test-double"}
+}
+
+func init() {
+	neural.RegisterActivator("test-double", func(params map[string]float64) (neural.Activator, error) {
+		return doubleActivator{}, nil
+	})
+}
+
+func TestRegisterActivator(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(doubleActivator{}))
+	nn.Layers()[0].SetWeights([][]float64{{3}}, []float64{1})
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, neural.SaveNetwork(nn, buffer))
+
+	loaded, err := neural.LoadNetwork(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{8}, loaded.Evaluate([]float64{1}))
+	assert.Contains(t, neural.ActivatorNames(), "test-double")
+
+	assert.Panics(t, func() {
+		neural.RegisterActivator("test-double", func(params map[string]float64) (neural.Activator, error) {
+			return doubleActivator{}, nil
+		})
+	})
+}
@@ -0,0 +1,133 @@
+package neural
+
+import "math"
+
+// RegressionMetrics describes how far network outputs are from expected ones
+type RegressionMetrics struct {
+	MSE         float64 `json:"mse"`           // Mean squared error
+	RMSE        float64 `json:"rmse"`          // Root of mean squared error
+	MAE         float64 `json:"mae"`           // Mean absolute error
+	R2          float64 `json:"r2"`            // Coefficient of determination
+	MaxAbsError float64 `json:"max_abs_error"` // Maximal absolute error
+}
+
+// RegressionReport describes quality of regression network (e.g. with linear activator in the last layer),
+// for every output separately and for all of them together. Overall R2 is mean of R2 of all outputs,
+// other overall metrics treat all values of all outputs equally.
+//
+// R2 of output with constant expected values is 1 if the output is exact and 0 otherwise.
+//
+// Samples with NaN or infinite value in network output or in expected output are not included in metrics,
+// they are counted in NonFinite only.
+type RegressionReport struct {
+	Samples   int                 `json:"samples"`
+	NonFinite int                 `json:"non_finite"`
+	Outputs   []RegressionMetrics `json:"outputs"`
+	Overall   RegressionMetrics   `json:"overall"`
+}
+
+// regressionSums accumulates errors and expected values of a single output
+type regressionSums struct {
+	squared, absolute, max float64
+	mean, variance         float64 // of expected values, variance is not normalized yet
+}
+
+func (s *regressionSums) add(actual, expected float64, samples int) {
+	diff := actual - expected
+	s.squared += diff * diff
+	s.absolute += math.Abs(diff)
+	s.max = math.Max(s.max, math.Abs(diff))
+
+	// Welford's algorithm, so values can be seen only once
+	delta := expected - s.mean
+	s.mean += delta / float64(samples)
+	s.variance += delta * (expected - s.mean)
+}
+
+func (s *regressionSums) metrics(samples int) RegressionMetrics {
+	if samples == 0 {
+		return RegressionMetrics{}
+	}
+
+	m := RegressionMetrics{
+		MSE:         s.squared / float64(samples),
+		MAE:         s.absolute / float64(samples),
+		MaxAbsError: s.max,
+	}
+	m.RMSE = math.Sqrt(m.MSE)
+
+	switch {
+	case s.variance != 0:
+		m.R2 = 1 - s.squared/s.variance
+	case s.squared == 0:
+		m.R2 = 1
+	}
+	return m
+}
+
+// EvaluateRegression evaluates network on all samples of dataset to build RegressionReport.
+// Error is returned if any sample could not be read, *ExampleError if it does not fit the network
+// and ErrNaNExpectedOutput if its Output has NaN value.
+func EvaluateRegression(nn Evaluator, dataset Dataset) (RegressionReport, error) {
+	layers := nn.Layers()
+	_, outputs := layers[len(layers)-1].Dimensions()
+
+	report := RegressionReport{
+		Samples: dataset.Len(),
+		Outputs: make([]RegressionMetrics, outputs, outputs),
+	}
+
+	sums := make([]regressionSums, outputs, outputs)
+	measured := 0
+	for i := 0; i < dataset.Len(); i++ {
+		sample, err := dataset.Example(i)
+		if err != nil {
+			return RegressionReport{}, err
+		}
+		if err := checkExample(nn, i, sample, true); err != nil {
+			return RegressionReport{}, err
+		}
+
+		for _, value := range sample.Output {
+			if math.IsNaN(value) {
+				return RegressionReport{}, ErrNaNExpectedOutput
+			}
+		}
+
+		output := nn.Evaluate(sample.Input)
+		if !finite(output) || !finite(sample.Output) {
+			report.NonFinite++
+			continue
+		}
+
+		measured++
+		for o := range sums {
+			sums[o].add(output[o], sample.Output[o], measured)
+		}
+	}
+
+	var overall regressionSums
+	for o := range sums {
+		report.Outputs[o] = sums[o].metrics(measured)
+		report.Overall.R2 += report.Outputs[o].R2 / float64(outputs)
+
+		overall.squared += sums[o].squared
+		overall.absolute += sums[o].absolute
+		overall.max = math.Max(overall.max, sums[o].max)
+	}
+
+	r2 := report.Overall.R2
+	report.Overall = overall.metrics(measured * outputs)
+	report.Overall.R2 = r2
+	return report, nil
+}
+
+// finite checks if all values are neither NaN nor infinite
+func finite(values []float64) bool {
+	for _, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return false
+		}
+	}
+	return true
+}
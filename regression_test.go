@@ -0,0 +1,127 @@
+package neural_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRegressionTestNetwork() neural.Evaluator {
+	// Outputs x and 2x
+	nn := neural.NewNeuralNetwork([]int{1, 2}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{1}, {2}}, []float64{0, 0})
+	return nn
+}
+
+func TestEvaluateRegression(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{1}, []float64{1, 1}},
+		{[]float64{2}, []float64{3, 4}},
+		{[]float64{3}, []float64{3, 8}},
+	}
+
+	report, err := neural.EvaluateRegression(newRegressionTestNetwork(), examples)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Samples)
+	assert.Len(t, report.Outputs, 2)
+
+	// Errors: 0, -1, 0
+	first := report.Outputs[0]
+	assert.InDelta(t, 1.0/3, first.MSE, 0.000001)
+	assert.InDelta(t, math.Sqrt(1.0/3), first.RMSE, 0.000001)
+	assert.InDelta(t, 1.0/3, first.MAE, 0.000001)
+	assert.InDelta(t, 0.625, first.R2, 0.000001)
+	assert.InDelta(t, 1, first.MaxAbsError, 0.000001)
+
+	// Errors: 1, 0, -2
+	second := report.Outputs[1]
+	assert.InDelta(t, 5.0/3, second.MSE, 0.000001)
+	assert.InDelta(t, math.Sqrt(5.0/3), second.RMSE, 0.000001)
+	assert.InDelta(t, 1, second.MAE, 0.000001)
+	assert.InDelta(t, 59.0/74, second.R2, 0.000001)
+	assert.InDelta(t, 2, second.MaxAbsError, 0.000001)
+
+	overall := report.Overall
+	assert.InDelta(t, 1, overall.MSE, 0.000001)
+	assert.InDelta(t, 1, overall.RMSE, 0.000001)
+	assert.InDelta(t, 2.0/3, overall.MAE, 0.000001)
+	assert.InDelta(t, (0.625+59.0/74)/2, overall.R2, 0.000001)
+	assert.InDelta(t, 2, overall.MaxAbsError, 0.000001)
+}
+
+func TestEvaluateRegressionConstantExpected(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{1}, []float64{1, 3}},
+		{[]float64{1}, []float64{1, 3}},
+	}
+
+	report, err := neural.EvaluateRegression(newRegressionTestNetwork(), examples)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, report.Outputs[0].R2)
+	assert.Equal(t, 0.0, report.Outputs[1].R2)
+}
+
+func TestEvaluateRegressionInvalidExample(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{1}, []float64{1, 3}},
+		{[]float64{1}, []float64{1}},
+	}
+
+	_, err := neural.EvaluateRegression(newRegressionTestNetwork(), examples)
+	assert.Equal(t, &neural.ExampleError{Index: 1, Validation: true, Inputs: 1, Outputs: 1, ExpectedInputs: 1, ExpectedOutputs: 2}, err)
+}
+
+func TestRegressionReportJSON(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{1}, []float64{1, 1}},
+		{[]float64{2}, []float64{3, 4}},
+	}
+
+	report, err := neural.EvaluateRegression(newRegressionTestNetwork(), examples)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(report)
+	assert.NoError(t, err)
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, 2.0, fields["samples"])
+	assert.Len(t, fields["outputs"], 2)
+	assert.Contains(t, fields["overall"], "max_abs_error")
+
+	var decoded neural.RegressionReport
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, report, decoded)
+}
+
+func TestEvaluateRegressionNonFinite(t *testing.T) {
+	examples := neural.SliceDataset{
+		{[]float64{1}, []float64{1, 1}},
+		{[]float64{math.NaN()}, []float64{1, 1}},
+		{[]float64{math.Inf(1)}, []float64{1, 1}},
+		{[]float64{1}, []float64{math.Inf(-1), 1}},
+		{[]float64{2}, []float64{3, 4}},
+	}
+
+	report, err := neural.EvaluateRegression(newRegressionTestNetwork(), examples)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Samples)
+	assert.Equal(t, 3, report.NonFinite)
+
+	// Only the first and the last sample are measured
+	assert.InDelta(t, 0.5, report.Outputs[0].MSE, 0.000001)
+	assert.InDelta(t, 0.5, report.Outputs[1].MSE, 0.000001)
+
+	data, err := json.Marshal(report)
+	assert.NoError(t, err)
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, 3.0, fields["non_finite"])
+
+	_, err = neural.EvaluateRegression(newRegressionTestNetwork(), neural.SliceDataset{{[]float64{1}, []float64{math.NaN(), 1}}})
+	assert.Equal(t, neural.ErrNaNExpectedOutput, err)
+}
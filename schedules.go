@@ -0,0 +1,181 @@
+package neural
+
+import "math"
+
+// LearningRateSchedule decides learning rate used for every mini-batch during training.
+// Epochs are counted from 1 (like in EpocheCallback), batches from 0. Batches is number of mini-batches in every epoch.
+type LearningRateSchedule interface {
+	LearningRate(epoch, batch, batches int) float64
+}
+
+// MetricObserver is implemented by LearningRateSchedule which adapts learning rate to validation metric.
+// Training reports validation cost (see EpocheStats.ValidationCost) to it at the end of every epoch.
+type MetricObserver interface {
+	Observe(metric float64)
+}
+
+// progress calculates how many epochs passed since the beginning of the training, including fraction of current epoch
+func progress(epoch, batch, batches int) float64 {
+	return float64(epoch-1) + float64(batch)/float64(batches)
+}
+
+type constantSchedule struct {
+	rate float64
+}
+
+// NewConstantSchedule creates schedule that keeps the same learning rate for the whole training.
+// It's used by Train if no schedule is selected.
+func NewConstantSchedule(rate float64) LearningRateSchedule {
+	return &constantSchedule{rate}
+}
+
+func (s *constantSchedule) LearningRate(epoch, batch, batches int) float64 {
+	return s.rate
+}
+
+type stepDecaySchedule struct {
+	rate   float64
+	factor float64
+	epochs int
+}
+
+// NewStepDecaySchedule creates schedule that multiplies learning rate by factor every given number of epochs
+//
+// Learning rate: rate * factor^floor((epoch-1)/epochs)
+//
+// Number of epochs lower than 1 is treated as 1 (decay every epoch).
+func NewStepDecaySchedule(rate, factor float64, epochs int) LearningRateSchedule {
+	if epochs < 1 {
+		epochs = 1
+	}
+	return &stepDecaySchedule{rate, factor, epochs}
+}
+
+func (s *stepDecaySchedule) LearningRate(epoch, batch, batches int) float64 {
+	return s.rate * math.Pow(s.factor, float64((epoch-1)/s.epochs))
+}
+
+type exponentialDecaySchedule struct {
+	rate  float64
+	decay float64
+}
+
+// NewExponentialDecaySchedule creates schedule that smoothly decays learning rate by given factor every epoch
+//
+// Learning rate: rate * decay^(epoch-1 + batch/batches)
+func NewExponentialDecaySchedule(rate, decay float64) LearningRateSchedule {
+	return &exponentialDecaySchedule{rate, decay}
+}
+
+func (s *exponentialDecaySchedule) LearningRate(epoch, batch, batches int) float64 {
+	return s.rate * math.Pow(s.decay, progress(epoch, batch, batches))
+}
+
+type cosineAnnealingSchedule struct {
+	maxRate, minRate float64
+	epochs           int
+	multiplier       int
+}
+
+// NewCosineAnnealingSchedule creates schedule that anneals learning rate from maxRate to minRate
+// following cosine curve, and then restarts it (SGDR). First period takes given number of epochs,
+// every next one is multiplier times longer.
+//
+// Learning rate: minRate + (maxRate-minRate) * (1 + cos(pi * t/period)) / 2, where t is time since last restart
+//
+// Number of epochs lower than 1 is treated as 1. Multiplier lower than 1 is treated as 1 (all periods equal).
+func NewCosineAnnealingSchedule(maxRate, minRate float64, epochs, multiplier int) LearningRateSchedule {
+	if epochs < 1 {
+		epochs = 1
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	return &cosineAnnealingSchedule{maxRate, minRate, epochs, multiplier}
+}
+
+func (s *cosineAnnealingSchedule) LearningRate(epoch, batch, batches int) float64 {
+	t := progress(epoch, batch, batches)
+	period := float64(s.epochs)
+	for t >= period {
+		t -= period
+		period *= float64(s.multiplier)
+	}
+
+	return s.minRate + (s.maxRate-s.minRate)*(1+math.Cos(math.Pi*t/period))/2
+}
+
+type warmupSchedule struct {
+	epochs   int
+	schedule LearningRateSchedule
+}
+
+// NewWarmupSchedule creates schedule that linearly increases learning rate from 0 to the one given by schedule
+// over given number of epochs. After that schedule is used directly.
+//
+// Number of epochs lower than 0 is treated as 0 (no warmup).
+func NewWarmupSchedule(epochs int, schedule LearningRateSchedule) LearningRateSchedule {
+	if epochs < 0 {
+		epochs = 0
+	}
+	return &warmupSchedule{epochs, schedule}
+}
+
+func (s *warmupSchedule) LearningRate(epoch, batch, batches int) float64 {
+	rate := s.schedule.LearningRate(epoch, batch, batches)
+	if epoch > s.epochs {
+		return rate
+	}
+
+	// Counting current batch, so the first one does not get 0
+	done := float64((epoch-1)*batches + batch + 1)
+	return rate * done / float64(s.epochs*batches)
+}
+
+// ReduceOnPlateauSchedule reduces learning rate when observed metric stops improving.
+// Train reports validation cost to it (see MetricObserver), so it needs validation examples.
+type ReduceOnPlateauSchedule struct {
+	rate     float64
+	factor   float64
+	patience int
+	minDelta float64
+	minRate  float64
+
+	best     float64
+	observed bool
+	waiting  int
+}
+
+// NewReduceOnPlateauSchedule creates schedule that multiplies learning rate by factor when metric
+// does not improve (decrease by more than minDelta) for patience observations in a row, like EarlyStopping.
+// Learning rate is never reduced below minRate.
+func NewReduceOnPlateauSchedule(rate, factor float64, patience int, minDelta, minRate float64) *ReduceOnPlateauSchedule {
+	return &ReduceOnPlateauSchedule{
+		rate:     rate,
+		factor:   factor,
+		patience: patience,
+		minDelta: minDelta,
+		minRate:  minRate,
+	}
+}
+
+// LearningRate returns current learning rate
+func (s *ReduceOnPlateauSchedule) LearningRate(epoch, batch, batches int) float64 {
+	return s.rate
+}
+
+// Observe reports latest value of the metric, lower values are considered better
+func (s *ReduceOnPlateauSchedule) Observe(metric float64) {
+	if !s.observed || metric < s.best-s.minDelta {
+		s.best = metric
+		s.observed = true
+		s.waiting = 0
+		return
+	}
+
+	s.waiting++
+	if s.waiting >= s.patience {
+		s.rate = math.Max(s.rate*s.factor, s.minRate)
+		s.waiting = 0
+	}
+}
@@ -0,0 +1,155 @@
+package neural_test
+
+import (
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstantSchedule(t *testing.T) {
+	schedule := neural.NewConstantSchedule(0.3)
+	assert.Equal(t, 0.3, schedule.LearningRate(1, 0, 10))
+	assert.Equal(t, 0.3, schedule.LearningRate(100, 9, 10))
+}
+
+func TestStepDecaySchedule(t *testing.T) {
+	schedule := neural.NewStepDecaySchedule(1, 0.5, 10)
+	testMatrix := []struct {
+		epoch, batch int
+		rate         float64
+	}{
+		{1, 0, 1},
+		{10, 9, 1},
+		{11, 0, 0.5},
+		{20, 5, 0.5},
+		{21, 0, 0.25},
+	}
+
+	for _, example := range testMatrix {
+		assert.InDelta(t, example.rate, schedule.LearningRate(example.epoch, example.batch, 10), 0.000001)
+	}
+}
+
+func TestExponentialDecaySchedule(t *testing.T) {
+	schedule := neural.NewExponentialDecaySchedule(2, 0.25)
+	assert.InDelta(t, 2, schedule.LearningRate(1, 0, 4), 0.000001)
+	assert.InDelta(t, 1, schedule.LearningRate(1, 2, 4), 0.000001)
+	assert.InDelta(t, 0.5, schedule.LearningRate(2, 0, 4), 0.000001)
+	assert.InDelta(t, 0.125, schedule.LearningRate(3, 0, 4), 0.000001)
+}
+
+func TestCosineAnnealingSchedule(t *testing.T) {
+	schedule := neural.NewCosineAnnealingSchedule(1, 0, 2, 2)
+	testMatrix := []struct {
+		epoch, batch int
+		rate         float64
+	}{
+		{1, 0, 1},
+		{2, 0, 0.5},
+		{2, 1, 0.146447},
+		// Restart, period of 4 epochs
+		{3, 0, 1},
+		{5, 0, 0.5},
+		{6, 1, 0.038060},
+		// Restart, period of 8 epochs
+		{7, 0, 1},
+		{11, 0, 0.5},
+	}
+
+	for _, example := range testMatrix {
+		assert.InDelta(t, example.rate, schedule.LearningRate(example.epoch, example.batch, 2), 0.000001, "epoch %v batch %v", example.epoch, example.batch)
+	}
+}
+
+func TestSchedulesInvalidEpochs(t *testing.T) {
+	// Treated as 1 epoch, or no warmup
+	stepDecay := neural.NewStepDecaySchedule(1, 0.5, 1)
+	cosine := neural.NewCosineAnnealingSchedule(1, 0, 1, 2)
+	for _, epochs := range []int{0, -1} {
+		for epoch := 1; epoch <= 4; epoch++ {
+			assert.Equal(t, stepDecay.LearningRate(epoch, 1, 2), neural.NewStepDecaySchedule(1, 0.5, epochs).LearningRate(epoch, 1, 2))
+			assert.Equal(t, cosine.LearningRate(epoch, 1, 2), neural.NewCosineAnnealingSchedule(1, 0, epochs, 2).LearningRate(epoch, 1, 2))
+			assert.Equal(t, 1.0, neural.NewWarmupSchedule(epochs, neural.NewConstantSchedule(1)).LearningRate(epoch, 0, 2))
+		}
+	}
+}
+
+func TestCosineAnnealingScheduleInvalidMultiplier(t *testing.T) {
+	// Treated as 1, every period takes 2 epochs
+	for _, multiplier := range []int{0, -2} {
+		schedule := neural.NewCosineAnnealingSchedule(1, 0, 2, multiplier)
+		assert.InDelta(t, 1, schedule.LearningRate(3, 0, 2), 0.000001)
+		assert.InDelta(t, 0.5, schedule.LearningRate(4, 0, 2), 0.000001)
+		assert.InDelta(t, 1, schedule.LearningRate(101, 0, 2), 0.000001)
+	}
+}
+
+func TestWarmupSchedule(t *testing.T) {
+	schedule := neural.NewWarmupSchedule(2, neural.NewConstantSchedule(1))
+	assert.InDelta(t, 0.25, schedule.LearningRate(1, 0, 2), 0.000001)
+	assert.InDelta(t, 0.5, schedule.LearningRate(1, 1, 2), 0.000001)
+	assert.InDelta(t, 0.75, schedule.LearningRate(2, 0, 2), 0.000001)
+	assert.InDelta(t, 1, schedule.LearningRate(2, 1, 2), 0.000001)
+	assert.InDelta(t, 1, schedule.LearningRate(3, 0, 2), 0.000001)
+}
+
+func TestReduceOnPlateauSchedule(t *testing.T) {
+	schedule := neural.NewReduceOnPlateauSchedule(1, 0.5, 2, 0.01, 0.2)
+
+	metrics := []float64{10, 9, 8.995, 8.999, 7, 7, 7, 7, 7, 7, 7, 7}
+	rates := []float64{1, 1, 1, 0.5, 0.5, 0.5, 0.25, 0.25, 0.2, 0.2, 0.2, 0.2}
+	for i, metric := range metrics {
+		schedule.Observe(metric)
+		assert.Equal(t, rates[i], schedule.LearningRate(i+2, 0, 1), "observation %v", i)
+	}
+}
+
+func TestTrainUsesSchedule(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{0}}, []float64{0})
+
+	// With learning rate 0 network does not change
+	example := neural.TrainExample{Input: []float64{1}, Output: []float64{1}}
+	options := neural.TrainOptions{
+		Epochs:               5,
+		MiniBatchSize:        1,
+		LearningRate:         1,
+		LearningRateSchedule: neural.NewConstantSchedule(0),
+		TrainerFactory:       neural.NewBackpropagationTrainer,
+		Cost:                 neural.NewQuadraticCost(),
+	}
+	neural.Train(nn, []neural.TrainExample{example}, options)
+	assert.Equal(t, []float64{0}, nn.Evaluate(example.Input))
+
+	// Only the first epoch changes the network
+	options.LearningRateSchedule = neural.NewStepDecaySchedule(0.25, 0, 1)
+	neural.Train(nn, []neural.TrainExample{example}, options)
+	assert.Equal(t, []float64{0.5}, nn.Evaluate(example.Input))
+}
+
+func TestTrainObservesValidationCost(t *testing.T) {
+	// Network is exact, so validation cost never improves
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{1}}, []float64{0})
+
+	examples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+	var rates []float64
+	options := neural.TrainOptions{
+		Epochs:               4,
+		MiniBatchSize:        1,
+		LearningRateSchedule: neural.NewReduceOnPlateauSchedule(1, 0.5, 1, 0, 0),
+		TrainerFactory:       neural.NewBackpropagationTrainer,
+		Cost:                 neural.NewQuadraticCost(),
+		ValidationExamples:   examples,
+		EpocheCallback: func(stats neural.EpocheStats) bool {
+			rates = append(rates, stats.LearningRate)
+			return false
+		},
+	}
+	assert.NoError(t, neural.Train(nn, examples, options))
+	assert.Equal(t, []float64{1, 1, 0.5, 0.25}, rates)
+
+	options.ValidationExamples = nil
+	assert.Equal(t, neural.ErrNoValidationExamples, neural.Train(nn, examples, options))
+}
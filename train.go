@@ -1,7 +1,11 @@
 package neural
 
 import (
+	"context"
+	"math"
 	"math/rand"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/mrfuxi/neural/mat"
@@ -13,8 +17,55 @@ type WeightUpdates struct {
 	Weights [][][]float64
 }
 
-// EpocheCallback gets called at the end of every epoche with information about the state of training
-type EpocheCallback func(epoche int, dt time.Duration)
+// EpocheStats describes state of the training at the end of an epoche
+type EpocheStats struct {
+	Epoche   int
+	Duration time.Duration
+
+	// Training metrics are measured on every sample when it's processed, before weights are updated by its mini-batch.
+	// They are calculated only if Cost implements Cost and Trainer implements OutputTrainer (BatchTrainer for network with BatchLayer).
+	HasTrainMetrics bool
+	TrainCost       float64
+	TrainErrorRate  float64
+
+	// Validation metrics are calculated for ValidationExamples (or ValidationDataset) at the end of the epoche,
+	// using EarlyStopping.Cost if set or Cost otherwise.
+	HasValidationMetrics bool
+	ValidationCost       float64
+	ValidationErrorRate  float64
+
+	LearningRate     float64   // Used for the last mini-batch
+	GradientNorms    []float64 // Per layer L2 norm of mean gradient of mini-batch, averaged over all mini-batches
+	SamplesPerSecond float64
+}
+
+// BatchStats describes mini-batch processed during training
+type BatchStats struct {
+	Epoche       int
+	Batch        int // Index of mini-batch in the epoche
+	From, To     int // Range of examples in the mini-batch, in order of the epoche (after shuffling)
+	LearningRate float64
+
+	// Set only after mini-batch is processed. Gradients are summed over all samples of the mini-batch,
+	// hook can modify them before they are passed to Optimizer.
+	// Zeroed gradients keep a layer frozen only with default optimizer without Momentum and Regularization,
+	// other optimizers still move weights by their accumulated state, Regularization shrinks them.
+	// They are reused by following mini-batches, so should not be kept after hook returns.
+	Gradients *WeightUpdates
+
+	// Mean cost and error rate of samples in the mini-batch, set only after mini-batch is processed,
+	// and only if metrics can be measured (see EpocheStats.HasTrainMetrics)
+	HasLoss   bool
+	Loss      float64
+	ErrorRate float64
+}
+
+// BatchCallback gets called before and after every mini-batch with information about it
+type BatchCallback func(stats BatchStats)
+
+// EpocheCallback gets called at the end of every epoche with information about the state of training.
+// Training stops (without error) when it returns true.
+type EpocheCallback func(stats EpocheStats) (stop bool)
 
 // NewWeightUpdates creates WeightUpdates according to structure of the network (neurons in each layer)
 func NewWeightUpdates(network Evaluator) WeightUpdates {
@@ -45,108 +96,487 @@ func (w *WeightUpdates) Zero() {
 	mat.ZeroVectorOfMatrixes(w.Weights)
 }
 
+// add sums other WeightUpdates into w
+func (w *WeightUpdates) add(other *WeightUpdates) {
+	for l := range w.Biases {
+		mat.SumMatrix(w.Weights[l], other.Weights[l])
+		mat.SumVector(w.Biases[l], other.Biases[l])
+	}
+}
+
 type batchRange struct {
 	from, to int
 }
 
 // TrainOptions define different switches used to train a network
 type TrainOptions struct {
-	Epochs         int
-	MiniBatchSize  int
-	LearningRate   float64
-	Regularization float64 // L2 labda value
-	Momentum       float64
-	TrainerFactory TrainerFactory
-	EpocheCallback EpocheCallback
-	Cost           CostDerivative
+	Epochs               int
+	MiniBatchSize        int
+	LearningRate         float64
+	LearningRateSchedule LearningRateSchedule // Overrides LearningRate if set
+	Regularization       float64              // L2 labda value
+	Momentum             float64              // Used only by default optimizer (NewMomentumOptimizer)
+	Optimizer            OptimizerFactory     // Update rule, stochastic gradient descent with Momentum if not set
+	TrainerFactory       TrainerFactory
+	EpocheCallback       EpocheCallback
+	BeforeBatch          BatchCallback // Called before mini-batch is processed
+	AfterBatch           BatchCallback // Called after gradients of mini-batch are calculated, before weights are updated
+	Cost                 CostDerivative
+	ValidationExamples   []TrainExample // Used by EarlyStopping, schedule implementing MetricObserver and to report validation metrics
+	ValidationDataset    Dataset        // Used instead of ValidationExamples if set
+	EarlyStopping        *EarlyStopping // Stop training when validation metric stops improving
+	Workers              int            // Number of samples processed concurrently, GOMAXPROCS if not set (1 for network with BatchLayer)
+	Random               *rand.Rand     // Used to shuffle examples, global source if not set
+}
+
+// validate checks if training can be run with the options for given network and examples
+func (options *TrainOptions) validate(network Evaluator, dataset Dataset) error {
+	if network == nil || len(network.Layers()) == 0 {
+		return ErrNoNetwork
+	}
+	if dataset == nil || dataset.Len() == 0 {
+		return ErrNoExamples
+	}
+	if options.MiniBatchSize <= 0 {
+		return ErrInvalidMiniBatchSize
+	}
+	if options.TrainerFactory == nil {
+		return ErrNoTrainerFactory
+	}
+	if options.Cost == nil {
+		return ErrNoCost
+	}
+
+	// Examples kept in memory are checked upfront, other datasets when examples are loaded
+	if examples, ok := dataset.(SliceDataset); ok {
+		if err := validateExamples(network, examples, false); err != nil {
+			return err
+		}
+	}
+	if examples, ok := options.validationDataset().(SliceDataset); ok {
+		return validateExamples(network, examples, true)
+	}
+	return nil
+}
+
+// validationDataset returns ValidationDataset, or ValidationExamples if it's not set
+func (options *TrainOptions) validationDataset() Dataset {
+	if options.ValidationDataset != nil {
+		return options.ValidationDataset
+	}
+	return SliceDataset(options.ValidationExamples)
+}
+
+// validateExamples checks if examples have as many inputs and outputs as the network
+func validateExamples(network Evaluator, examples []TrainExample, validation bool) error {
+	for i, example := range examples {
+		if err := checkExample(network, i, example, validation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkExample checks if example has as many inputs and outputs as the network
+func checkExample(network Evaluator, index int, example TrainExample, validation bool) error {
+	layers := network.Layers()
+	inputs, _ := layers[0].Dimensions()
+	_, outputs := layers[len(layers)-1].Dimensions()
+
+	if len(example.Input) != inputs || len(example.Output) != outputs {
+		return &ExampleError{
+			Index:           index,
+			Validation:      validation,
+			Inputs:          len(example.Input),
+			Outputs:         len(example.Output),
+			ExpectedInputs:  inputs,
+			ExpectedOutputs: outputs,
+		}
+	}
+	return nil
 }
 
 // Train executes training algorithm using provided Trainers (build with TrainerFactory)
-// Training happens in randomized batches where samples are processed concurrently
-func Train(network Evaluator, trainExamples []TrainExample, options TrainOptions) {
-	batchRanges := getBatchRanges(len(trainExamples), options.MiniBatchSize)
-	ready := make(chan int, options.MiniBatchSize)
+// Training happens in randomized batches where samples are processed concurrently.
+// It runs for all Epochs, unless EarlyStopping stops it sooner.
+//
+// Training is deterministic: network built from layers with own sources of random numbers
+// (e.g. NewFullyConnectedLayerFrom) and trained with the same Random and Workers ends up with identical weights.
+// Error is returned if options or examples do not fit the network, and then the network is not changed.
+func Train(network Evaluator, trainExamples []TrainExample, options TrainOptions) error {
+	return TrainContext(context.Background(), network, trainExamples, options)
+}
+
+// TrainContext works like Train, but stops when ctx is cancelled and returns ctx.Err().
+// Cancellation is checked between mini-batches, so every mini-batch is either fully applied to the network or not at all,
+// and no sample is processed when TrainContext returns. Weights of the best epoch are restored
+// if EarlyStopping.RestoreBest is set.
+func TrainContext(ctx context.Context, network Evaluator, trainExamples []TrainExample, options TrainOptions) error {
+	return TrainDataset(ctx, network, SliceDataset(trainExamples), options)
+}
+
+// TrainDataset works like TrainContext, but takes examples from dataset, loading only one mini-batch at a time.
+// Examples of datasets other than SliceDataset (training and validation ones) are checked when loaded, so invalid example
+// or error of the dataset can stop training after some mini-batches were applied to the network.
+func TrainDataset(ctx context.Context, network Evaluator, dataset Dataset, options TrainOptions) error {
+	if err := options.validate(network, dataset); err != nil {
+		return err
+	}
+
+	earlyStopper, err := newEarlyStopper(options)
+	if err != nil {
+		return err
+	}
+
+	examples := dataset.Len()
+	batchRanges := getBatchRanges(examples, options.MiniBatchSize)
+
+	// Datasets which can not shuffle themselves are read in random order
+	shuffler, _ := dataset.(Shuffler)
+	var order []int
+	if shuffler == nil {
+		order = make([]int, examples, examples)
+		for i := range order {
+			order[i] = i
+		}
+	}
+	batchSamples := make([]TrainExample, options.MiniBatchSize, options.MiniBatchSize)
 
 	layers := network.Layers()
 
-	trainers := make([]Trainer, options.MiniBatchSize, options.MiniBatchSize)
-	for i := range trainers {
-		trainers[i] = options.TrainerFactory(network, options.Cost)
+	workersCount := options.Workers
+	if workersCount <= 0 {
+		workersCount = runtime.GOMAXPROCS(0)
+	}
+	if workersCount > options.MiniBatchSize {
+		workersCount = options.MiniBatchSize
+	}
+	if hasBatchLayer(network) {
+		workersCount = 1
+	}
+
+	workers := make([]trainWorker, workersCount, workersCount)
+	for i := range workers {
+		workers[i] = newTrainWorker(network, options)
+	}
+	if hasBatchLayer(network) && workers[0].batch == nil {
+		return ErrNoBatchTrainer
 	}
 
-	weightUpdates := make([]WeightUpdates, options.MiniBatchSize, options.MiniBatchSize)
-	for i := range weightUpdates {
-		weightUpdates[i] = NewWeightUpdates(network)
+	optimizerFactory := options.Optimizer
+	if optimizerFactory == nil {
+		optimizerFactory = NewMomentumOptimizer(options.Momentum)
 	}
+	optimizer := optimizerFactory(network)
+	lookAhead, _ := optimizer.(LookAheadOptimizer)
 
-	sumWeights := NewWeightUpdates(network)
-	momentumWeights := NewWeightUpdates(network)
+	schedule := options.LearningRateSchedule
+	if schedule == nil {
+		schedule = NewConstantSchedule(options.LearningRate)
+	}
+	observer, _ := schedule.(MetricObserver)
+
+	validation := options.validationDataset()
+	var validationCost Cost
+	if earlyStopper != nil {
+		validationCost = earlyStopper.Cost
+	} else if cost, ok := options.Cost.(Cost); ok && (options.EpocheCallback != nil || observer != nil) && validation.Len() > 0 {
+		validationCost = cost
+	}
+	if observer != nil && validation.Len() == 0 {
+		return ErrNoValidationExamples
+	}
+	if observer != nil && validationCost == nil {
+		return ErrNoValidationCost
+	}
 
-	weightsDecay := 1 - (options.LearningRate*options.Regularization)/float64(len(trainExamples))
+	sumWeights := NewWeightUpdates(network)
+	var learningRate float64
 
 	for epoch := 1; epoch <= options.Epochs; epoch++ {
-		shuffleTrainExamples(trainExamples)
+		if shuffler != nil {
+			shuffler.Shuffle(options.Random)
+		} else {
+			shuffleOrder(order, options.Random)
+		}
+		var trainCost, trainErrors float64
+		gradientNorms := make([]float64, len(layers), len(layers))
 		t0 := time.Now()
 
-		for _, batch := range batchRanges {
-			samples := trainExamples[batch.from:batch.to]
-			for i := range samples {
-				go func(i int) {
-					trainers[i].Process(samples[i], &weightUpdates[i])
-					ready <- i
-				}(i)
+		for b, batch := range batchRanges {
+			select {
+			case <-ctx.Done():
+				if err := earlyStopper.restore(network); err != nil {
+					return err
+				}
+				return ctx.Err()
+			default:
+			}
+
+			samples := batchSamples[:batch.to-batch.from]
+			if err := loadBatch(network, dataset, order, batch, samples); err != nil {
+				return err
+			}
+
+			learningRate = schedule.LearningRate(epoch, b, len(batchRanges))
+			weightsDecay := 1 - (learningRate*options.Regularization)/float64(examples)
+
+			batchStats := BatchStats{
+				Epoche:       epoch,
+				Batch:        b,
+				From:         batch.from,
+				To:           batch.to,
+				LearningRate: learningRate,
+			}
+			if options.BeforeBatch != nil {
+				options.BeforeBatch(batchStats)
+			}
+
+			var shift *WeightUpdates
+			if lookAhead != nil {
+				shift = lookAhead.LookAhead()
+				shiftWeights(layers, shift, 1)
 			}
 
+			processBatch(workers, samples)
+
+			// Reduction in fixed order of workers
 			sumWeights.Zero()
 			batchSize := batch.to - batch.from
-			processed := 0
-			for i := range ready {
-				weightUpdate := weightUpdates[i]
-				for l := range layers {
-					mat.SumMatrix(sumWeights.Weights[l], weightUpdate.Weights[l])
-					mat.SumVector(sumWeights.Biases[l], weightUpdate.Biases[l])
-				}
-				processed++
-				if processed == batchSize {
-					break
+			var batchCost, batchErrors float64
+			for w := range workers {
+				if workers[w].processed > 0 {
+					sumWeights.add(&workers[w].sum)
+					batchCost += workers[w].cost
+					batchErrors += workers[w].errors
 				}
 			}
+			trainCost += batchCost
+			trainErrors += batchErrors
 
-			rate := -options.LearningRate / float64(batchSize)
-			for l, layer := range layers {
-				// dx = -(LR/batchSize) * W
-				mat.MulVectorByScalar(sumWeights.Biases[l], rate)
-				mat.MulMatrixByScalar(sumWeights.Weights[l], rate)
+			if shift != nil {
+				shiftWeights(layers, shift, -1)
+			}
 
-				// v = momentum * v
-				mat.MulVectorByScalar(momentumWeights.Biases[l], options.Momentum)
-				mat.MulMatrixByScalar(momentumWeights.Weights[l], options.Momentum)
+			if options.AfterBatch != nil {
+				batchStats.Gradients = &sumWeights
+				batchStats.HasLoss = workers[0].measuring()
+				batchStats.Loss = batchCost / float64(batchSize)
+				batchStats.ErrorRate = batchErrors / float64(batchSize)
+				options.AfterBatch(batchStats)
+			}
 
-				// v = v + dx
-				mat.SumVector(momentumWeights.Biases[l], sumWeights.Biases[l])
-				mat.SumMatrix(momentumWeights.Weights[l], sumWeights.Weights[l])
+			if options.EpocheCallback != nil {
+				addGradientNorms(gradientNorms, &sumWeights, batchSize)
+			}
 
-				// W = W + v
+			optimizer.Update(&sumWeights, batchSize, learningRate)
+			for l, layer := range layers {
 				// L2 used for weighs only
-				layer.UpdateWeights(momentumWeights.Weights[l], momentumWeights.Biases[l], weightsDecay)
+				layer.UpdateWeights(sumWeights.Weights[l], sumWeights.Biases[l], weightsDecay)
 			}
 		}
 
-		if options.EpocheCallback != nil {
-			dt := time.Since(t0)
-			options.EpocheCallback(epoch, dt)
+		dt := time.Since(t0)
+		stats := EpocheStats{
+			Epoche:           epoch,
+			Duration:         dt,
+			HasTrainMetrics:  workers[0].measuring(),
+			TrainCost:        trainCost / float64(examples),
+			TrainErrorRate:   trainErrors / float64(examples),
+			LearningRate:     learningRate,
+			GradientNorms:    gradientNorms,
+			SamplesPerSecond: float64(examples) / dt.Seconds(),
 		}
+		mat.MulVectorByScalar(stats.GradientNorms, 1/float64(len(batchRanges)))
+
+		if validationCost != nil {
+			stats.HasValidationMetrics = true
+			stats.ValidationCost, stats.ValidationErrorRate, err = CalculateDatasetCorrectness(network, validationCost, validation)
+			if err != nil {
+				return err
+			}
+		}
+
+		stop := false
+		if earlyStopper != nil {
+			if stop, err = earlyStopper.observe(network, stats); err != nil {
+				return err
+			}
+		}
+		if options.EpocheCallback != nil && options.EpocheCallback(stats) {
+			stop = true
+		}
+		if stop {
+			return earlyStopper.restore(network)
+		}
+
+		if observer != nil {
+			observer.Observe(stats.ValidationCost)
+		}
+	}
+
+	return earlyStopper.restore(network)
+}
+
+// trainWorker processes part of every mini-batch with its own Trainer,
+// accumulating weight updates of all its samples locally
+type trainWorker struct {
+	trainer       Trainer
+	batch         BatchTrainer  // Set if trainer can process whole mini-batch, used only for networks with BatchLayer
+	weightUpdates WeightUpdates // of a single sample
+	sum           WeightUpdates // of all samples processed in current mini-batch
+	processed     int
+
+	// Metrics of samples processed in current mini-batch
+	output       OutputTrainer
+	costFunction Cost
+	cost         float64
+	errors       float64
+}
+
+func newTrainWorker(network Evaluator, options TrainOptions) trainWorker {
+	w := trainWorker{
+		trainer:       options.TrainerFactory(network, options.Cost),
+		weightUpdates: NewWeightUpdates(network),
+		sum:           NewWeightUpdates(network),
+	}
+	w.output, _ = w.trainer.(OutputTrainer)
+	if hasBatchLayer(network) {
+		w.batch, _ = w.trainer.(BatchTrainer)
+	}
+	w.costFunction, _ = options.Cost.(Cost)
+	return w
+}
+
+func (w *trainWorker) process(samples []TrainExample) {
+	if w.batch != nil {
+		outputs := w.batch.ProcessBatch(samples, &w.sum)
+		for s, sample := range samples {
+			w.measure(outputs[s], sample)
+		}
+		return
+	}
+
+	// First sample goes directly to the sum, saving zeroing and adding it
+	w.trainer.Process(samples[0], &w.sum)
+	w.measureLast(samples[0])
+	for _, sample := range samples[1:] {
+		w.trainer.Process(sample, &w.weightUpdates)
+		w.measureLast(sample)
+		w.sum.add(&w.weightUpdates)
+	}
+}
+
+// measuring tells if worker can measure cost and errors of processed samples
+func (w *trainWorker) measuring() bool {
+	return (w.output != nil || w.batch != nil) && w.costFunction != nil
+}
+
+// measureLast adds cost and error of just processed sample to metrics of the mini-batch
+func (w *trainWorker) measureLast(sample TrainExample) {
+	if w.output != nil {
+		w.measure(w.output.Output(), sample)
+	}
+}
+
+// measure adds cost and error of a sample with given output of the network to metrics of the mini-batch
+func (w *trainWorker) measure(output []float64, sample TrainExample) {
+	if !w.measuring() {
+		return
+	}
+
+	w.cost += w.costFunction.Cost(output, sample.Output)
+	if mat.ArgMax(output) != mat.ArgMax(sample.Output) {
+		w.errors++
+	}
+}
+
+// addGradientNorms adds L2 norm of mean gradient of every layer to norms
+func addGradientNorms(norms []float64, gradients *WeightUpdates, batchSize int) {
+	for l := range norms {
+		sum := 0.0
+		for _, row := range gradients.Weights[l] {
+			for _, value := range row {
+				sum += value * value
+			}
+		}
+		for _, value := range gradients.Biases[l] {
+			sum += value * value
+		}
+		norms[l] += math.Sqrt(sum) / float64(batchSize)
+	}
+}
+
+// processBatch splits samples into continuous, equal parts processed concurrently by workers
+func processBatch(workers []trainWorker, samples []TrainExample) {
+	var wg sync.WaitGroup
+	for w := range workers {
+		from := w * len(samples) / len(workers)
+		to := (w + 1) * len(samples) / len(workers)
+		workers[w].processed = to - from
+		workers[w].cost, workers[w].errors = 0, 0
+		if from == to {
+			continue
+		}
+
+		wg.Add(1)
+		go func(worker *trainWorker, samples []TrainExample) {
+			defer wg.Done()
+			worker.process(samples)
+		}(&workers[w], samples[from:to])
+	}
+	wg.Wait()
+}
+
+// shiftWeights adds shift multiplied by scale to weights of every layer. Shift is modified in place.
+func shiftWeights(layers []Layer, shift *WeightUpdates, scale float64) {
+	for l, layer := range layers {
+		if scale != 1 {
+			mat.MulVectorByScalar(shift.Biases[l], scale)
+			mat.MulMatrixByScalar(shift.Weights[l], scale)
+		}
+		layer.UpdateWeights(shift.Weights[l], shift.Biases[l], 1)
 	}
 }
 
-func shuffleTrainExamples(trainExamples []TrainExample) {
-	for i := range trainExamples {
-		j := rand.Intn(i + 1)
-		trainExamples[i], trainExamples[j] = trainExamples[j], trainExamples[i]
+// loadBatch reads examples of the batch from dataset into samples, following order if it's set
+func loadBatch(network Evaluator, dataset Dataset, order []int, batch batchRange, samples []TrainExample) error {
+	for k := batch.from; k < batch.to; k++ {
+		i := k
+		if order != nil {
+			i = order[k]
+		}
+
+		example, err := dataset.Example(i)
+		if err != nil {
+			return err
+		}
+		if err := checkExample(network, i, example, false); err != nil {
+			return err
+		}
+		samples[k-batch.from] = example
+	}
+	return nil
+}
+
+func shuffleOrder(order []int, random *rand.Rand) {
+	intn := rand.Intn
+	if random != nil {
+		intn = random.Intn
+	}
+
+	for i := range order {
+		j := intn(i + 1)
+		order[i], order[j] = order[j], order[i]
 	}
 }
 
 func getBatchRanges(samples, miniBatchSize int) []batchRange {
+	if samples <= 0 || miniBatchSize <= 0 {
+		return nil
+	}
+
 	batches := samples / miniBatchSize
 	if samples%miniBatchSize != 0 {
 		batches++
@@ -165,12 +595,31 @@ func getBatchRanges(samples, miniBatchSize int) []batchRange {
 	return batchRanges
 }
 
-// CalculateCorrectness evaluates neural network across test samples to give averate cost and error rate
+// CalculateCorrectness evaluates neural network across test samples to give averate cost and error rate.
+// It panics if any sample does not fit the network, see CalculateDatasetCorrectness.
 func CalculateCorrectness(nn Evaluator, cost Cost, samples []TrainExample) (avgCost float64, errors float64) {
+	avgCost, errors, err := CalculateDatasetCorrectness(nn, cost, SliceDataset(samples))
+	if err != nil {
+		panic(err.Error())
+	}
+	return
+}
+
+// CalculateDatasetCorrectness works like CalculateCorrectness, but takes samples from dataset.
+// Error is returned if any sample could not be read, *ExampleError if it does not fit the network.
+func CalculateDatasetCorrectness(nn Evaluator, cost Cost, dataset Dataset) (avgCost float64, errors float64, err error) {
 	var sum float64
 	var different float64
 
-	for _, sample := range samples {
+	for i := 0; i < dataset.Len(); i++ {
+		sample, err := dataset.Example(i)
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := checkExample(nn, i, sample, true); err != nil {
+			return 0, 0, err
+		}
+
 		output := nn.Evaluate(sample.Input)
 		sum += cost.Cost(output, sample.Output)
 
@@ -179,7 +628,17 @@ func CalculateCorrectness(nn Evaluator, cost Cost, samples []TrainExample) (avgC
 		}
 	}
 
-	avgCost = sum / float64(len(samples))
-	errors = different / float64(len(samples))
+	avgCost = sum / float64(dataset.Len())
+	errors = different / float64(dataset.Len())
 	return
 }
+
+// hasBatchLayer tells if any layer of the network is BatchLayer
+func hasBatchLayer(network Evaluator) bool {
+	for _, layer := range network.Layers() {
+		if _, ok := layer.(BatchLayer); ok {
+			return true
+		}
+	}
+	return false
+}
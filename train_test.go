@@ -0,0 +1,399 @@
+package neural_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mrfuxi/neural"
+	"github.com/mrfuxi/neural/mat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrainContextCancelled(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{0}}, []float64{0})
+	examples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	options := neural.TrainOptions{
+		Epochs:         10,
+		MiniBatchSize:  1,
+		LearningRate:   0.1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+	}
+	err := neural.TrainContext(ctx, nn, examples, options)
+	assert.Equal(t, context.Canceled, err)
+
+	// Nothing was trained
+	assert.Equal(t, []float64{0}, nn.Evaluate(examples[0].Input))
+}
+
+func TestTrainContextCancelledDuringTraining(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 3, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()), neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	examples := []neural.TrainExample{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 1}, []float64{0}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	epochs := 0
+	options := neural.TrainOptions{
+		Epochs:         1000,
+		MiniBatchSize:  2,
+		LearningRate:   1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+		EpocheCallback: func(stats neural.EpocheStats) bool {
+			epochs = stats.Epoche
+			if stats.Epoche == 3 {
+				cancel()
+			}
+			return false
+		},
+	}
+	err := neural.TrainContext(ctx, nn, examples, options)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 3, epochs)
+}
+
+func TestTrainContextCompleted(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	examples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+
+	options := neural.TrainOptions{
+		Epochs:         10,
+		MiniBatchSize:  1,
+		LearningRate:   0.1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+	}
+	assert.NoError(t, neural.TrainContext(context.Background(), nn, examples, options))
+}
+
+func TestTrainErrors(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{2, 1}, neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()))
+	examples := []neural.TrainExample{{Input: []float64{1, 0}, Output: []float64{1}}}
+	valid := neural.TrainOptions{
+		Epochs:         1,
+		MiniBatchSize:  1,
+		LearningRate:   1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+	}
+
+	noMiniBatch := valid
+	noMiniBatch.MiniBatchSize = 0
+	noTrainer := valid
+	noTrainer.TrainerFactory = nil
+	noCost := valid
+	noCost.Cost = nil
+	badValidation := valid
+	badValidation.ValidationExamples = []neural.TrainExample{examples[0], {Input: []float64{1, 0}, Output: []float64{1, 0}}}
+
+	testMatrix := []struct {
+		network  neural.Evaluator
+		examples []neural.TrainExample
+		options  neural.TrainOptions
+		err      error
+	}{
+		{nil, examples, valid, neural.ErrNoNetwork},
+		{nn, nil, valid, neural.ErrNoExamples},
+		{nn, examples, noMiniBatch, neural.ErrInvalidMiniBatchSize},
+		{nn, examples, noTrainer, neural.ErrNoTrainerFactory},
+		{nn, examples, noCost, neural.ErrNoCost},
+		{
+			nn, []neural.TrainExample{examples[0], {Input: []float64{1}, Output: []float64{1}}}, valid,
+			&neural.ExampleError{Index: 1, Inputs: 1, Outputs: 1, ExpectedInputs: 2, ExpectedOutputs: 1},
+		},
+		{
+			nn, examples, badValidation,
+			&neural.ExampleError{Index: 1, Validation: true, Inputs: 2, Outputs: 2, ExpectedInputs: 2, ExpectedOutputs: 1},
+		},
+	}
+
+	for _, example := range testMatrix {
+		assert.Equal(t, example.err, neural.Train(example.network, example.examples, example.options))
+	}
+
+	assert.NoError(t, neural.Train(nn, examples, valid))
+}
+
+func TestExampleErrorMessage(t *testing.T) {
+	err := &neural.ExampleError{Index: 3, Validation: true, Inputs: 1, Outputs: 2, ExpectedInputs: 3, ExpectedOutputs: 4}
+	assert.EqualError(t, err, "validation example 3 has 1 inputs and 2 outputs, network expects 3 inputs and 4 outputs")
+}
+
+func TestTrainWorkers(t *testing.T) {
+	examples := make([]neural.TrainExample, 7)
+	for i := range examples {
+		examples[i] = neural.TrainExample{Input: []float64{float64(i)}, Output: []float64{1}}
+	}
+
+	// Any number of workers gives the same result (up to rounding)
+	var expected []float64
+	for _, workers := range []int{0, 1, 2, 3, 7, 100} {
+		nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+		nn.Layers()[0].SetWeights([][]float64{{0.5}}, []float64{0.5})
+
+		options := neural.TrainOptions{
+			Epochs:         3,
+			MiniBatchSize:  7,
+			LearningRate:   0.01,
+			TrainerFactory: neural.NewBackpropagationTrainer,
+			Cost:           neural.NewQuadraticCost(),
+			Workers:        workers,
+		}
+		assert.NoError(t, neural.Train(nn, examples, options))
+
+		output := nn.Evaluate([]float64{1})
+		if expected == nil {
+			expected = output
+		}
+		assert.InDeltaSlice(t, expected, output, 0.000000001, "workers: %v", workers)
+	}
+}
+
+func benchmarkTrainMNISTSized(b *testing.B, miniBatchSize, workers int) {
+	examples := make([]neural.TrainExample, 1024)
+	for i := range examples {
+		examples[i] = neural.TrainExample{Input: mat.RandomVector(784), Output: make([]float64, 10)}
+		examples[i].Output[i%10] = 1
+	}
+
+	nn := neural.NewNeuralNetwork(
+		[]int{784, 100, 10},
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+		neural.NewFullyConnectedLayer(neural.NewSoftmaxActivator()),
+	)
+
+	options := neural.TrainOptions{
+		Epochs:         1,
+		MiniBatchSize:  miniBatchSize,
+		LearningRate:   0.1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewLogLikelihoodCost(),
+		Workers:        workers,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		neural.Train(nn, examples, options)
+	}
+}
+
+// Worker per sample, like training used to work before worker pool
+func BenchmarkTrainBatch10WorkerPerSample(b *testing.B)  { benchmarkTrainMNISTSized(b, 10, 10) }
+func BenchmarkTrainBatch10(b *testing.B)                 { benchmarkTrainMNISTSized(b, 10, 0) }
+func BenchmarkTrainBatch256WorkerPerSample(b *testing.B) { benchmarkTrainMNISTSized(b, 256, 256) }
+func BenchmarkTrainBatch256(b *testing.B)                { benchmarkTrainMNISTSized(b, 256, 0) }
+func BenchmarkTrainBatch256SingleWorker(b *testing.B)    { benchmarkTrainMNISTSized(b, 256, 1) }
+
+func TestTrainDeterministic(t *testing.T) {
+	examples := []neural.TrainExample{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 1}, []float64{0}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+		{[]float64{0.5, 0.5}, []float64{1}},
+	}
+
+	train := func() []byte {
+		// Examples are shuffled in place, so every run starts from the same order
+		examples := append([]neural.TrainExample(nil), examples...)
+		random := rand.New(rand.NewSource(42))
+		nn := neural.NewNeuralNetwork(
+			[]int{2, 8, 8, 1},
+			neural.NewFullyConnectedLayerFrom(neural.NewSigmoidActivator(), random),
+			neural.NewDropoutLayerFrom(0.5, random),
+			neural.NewFullyConnectedLayerFrom(neural.NewSigmoidActivator(), random),
+		)
+
+		options := neural.TrainOptions{
+			Epochs:         20,
+			MiniBatchSize:  4,
+			LearningRate:   0.5,
+			TrainerFactory: neural.NewBackpropagationTrainer,
+			Cost:           neural.NewCrossEntropyCost(),
+			Workers:        3,
+			Random:         random,
+		}
+		assert.NoError(t, neural.Train(nn, examples, options))
+
+		buffer := new(bytes.Buffer)
+		assert.NoError(t, neural.Save(nn, buffer))
+		return buffer.Bytes()
+	}
+
+	// Global source should not matter
+	rand.Seed(1)
+	first := train()
+	rand.Seed(2)
+	second := train()
+
+	assert.Equal(t, first, second)
+}
+
+func TestEpocheStats(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{0}}, []float64{0})
+	examples := []neural.TrainExample{
+		{Input: []float64{1}, Output: []float64{1}},
+		{Input: []float64{2}, Output: []float64{1}},
+	}
+	validationExamples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{2}}}
+	cost := neural.NewQuadraticCost()
+
+	var stats []neural.EpocheStats
+	options := neural.TrainOptions{
+		Epochs:             10,
+		MiniBatchSize:      1,
+		LearningRate:       0,
+		TrainerFactory:     neural.NewBackpropagationTrainer,
+		Cost:               cost,
+		ValidationExamples: validationExamples,
+		EpocheCallback: func(epocheStats neural.EpocheStats) bool {
+			stats = append(stats, epocheStats)
+			return epocheStats.Epoche == 2
+		},
+	}
+	assert.NoError(t, neural.Train(nn, examples, options))
+
+	// Stopped by callback
+	assert.Len(t, stats, 2)
+
+	// Network does not change with learning rate 0
+	trainCost, trainErrors := neural.CalculateCorrectness(nn, cost, examples)
+	validationCost, validationErrors := neural.CalculateCorrectness(nn, cost, validationExamples)
+	for i, epocheStats := range stats {
+		assert.Equal(t, i+1, epocheStats.Epoche)
+		assert.True(t, epocheStats.HasTrainMetrics)
+		assert.InDelta(t, trainCost, epocheStats.TrainCost, 0.000001)
+		assert.Equal(t, trainErrors, epocheStats.TrainErrorRate)
+		assert.True(t, epocheStats.HasValidationMetrics)
+		assert.Equal(t, validationCost, epocheStats.ValidationCost)
+		assert.Equal(t, validationErrors, epocheStats.ValidationErrorRate)
+		assert.Equal(t, 0.0, epocheStats.LearningRate)
+		assert.True(t, epocheStats.SamplesPerSecond > 0)
+
+		// Gradients of samples are (-1, -1) and (-2, -1)
+		assert.Len(t, epocheStats.GradientNorms, 1)
+		assert.InDelta(t, (math.Sqrt(2)+math.Sqrt(5))/2, epocheStats.GradientNorms[0], 0.000001)
+	}
+}
+
+func TestEpocheStatsWithoutValidation(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	examples := []neural.TrainExample{{Input: []float64{1}, Output: []float64{1}}}
+
+	epochs := 0
+	options := neural.TrainOptions{
+		Epochs:         3,
+		MiniBatchSize:  1,
+		LearningRate:   0.1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+		EpocheCallback: func(stats neural.EpocheStats) bool {
+			epochs++
+			assert.False(t, stats.HasValidationMetrics)
+			return false
+		},
+	}
+	assert.NoError(t, neural.Train(nn, examples, options))
+	assert.Equal(t, 3, epochs)
+}
+
+func TestBatchHooks(t *testing.T) {
+	nn := neural.NewNeuralNetwork([]int{1, 1}, neural.NewFullyConnectedLayer(neural.NewLinearActivator(1)))
+	nn.Layers()[0].SetWeights([][]float64{{0}}, []float64{0})
+	examples := []neural.TrainExample{
+		{Input: []float64{1}, Output: []float64{1}},
+		{Input: []float64{1}, Output: []float64{1}},
+		{Input: []float64{1}, Output: []float64{1}},
+	}
+
+	calls := []string{}
+	var before, after []neural.BatchStats
+	var gradients [][]float64
+	options := neural.TrainOptions{
+		Epochs:         2,
+		MiniBatchSize:  2,
+		LearningRate:   0,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+		BeforeBatch: func(stats neural.BatchStats) {
+			calls = append(calls, "before")
+			before = append(before, stats)
+		},
+		AfterBatch: func(stats neural.BatchStats) {
+			calls = append(calls, "after")
+			after = append(after, stats)
+			gradients = append(gradients, []float64{stats.Gradients.Weights[0][0][0], stats.Gradients.Biases[0][0]})
+		},
+	}
+	assert.NoError(t, neural.Train(nn, examples, options))
+
+	assert.Equal(t, []string{"before", "after", "before", "after", "before", "after", "before", "after"}, calls)
+	ranges := [][]int{{1, 0, 0, 2}, {1, 1, 2, 3}, {2, 0, 0, 2}, {2, 1, 2, 3}}
+	for i, expected := range ranges {
+		for _, stats := range []neural.BatchStats{before[i], after[i]} {
+			assert.Equal(t, expected, []int{stats.Epoche, stats.Batch, stats.From, stats.To})
+		}
+		assert.Nil(t, before[i].Gradients)
+		assert.False(t, before[i].HasLoss)
+
+		// Every sample has output 0 instead of 1
+		batchSize := float64(expected[3] - expected[2])
+		assert.True(t, after[i].HasLoss)
+		assert.Equal(t, 0.5, after[i].Loss)
+		assert.Equal(t, []float64{-batchSize, -batchSize}, gradients[i])
+	}
+}
+
+func TestAfterBatchFreezeLayer(t *testing.T) {
+	nn := neural.NewNeuralNetwork(
+		[]int{2, 3, 1},
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+		neural.NewFullyConnectedLayer(neural.NewSigmoidActivator()),
+	)
+	examples := []neural.TrainExample{
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{0}},
+	}
+	input := []float64{0, 1}
+
+	// Weights of first layer do not change (plain gradient descent without momentum and regularization),
+	// so its output stays the same
+	output := func() []float64 {
+		potentials := make([]float64, 3)
+		nn.Layers()[0].Forward(potentials, input)
+		return potentials
+	}
+	expected := output()
+
+	options := neural.TrainOptions{
+		Epochs:         10,
+		MiniBatchSize:  1,
+		LearningRate:   1,
+		TrainerFactory: neural.NewBackpropagationTrainer,
+		Cost:           neural.NewQuadraticCost(),
+		AfterBatch: func(stats neural.BatchStats) {
+			mat.ZeroMatrix(stats.Gradients.Weights[0])
+			mat.ZeroVector(stats.Gradients.Biases[0])
+		},
+	}
+	before := nn.Evaluate(input)
+	assert.NoError(t, neural.Train(nn, examples, options))
+
+	assert.Equal(t, expected, output())
+	assert.NotEqual(t, before, nn.Evaluate(input))
+}
@@ -8,6 +8,22 @@ type Trainer interface {
 	Process(sample TrainExample, weightUpdates *WeightUpdates)
 }
 
+// OutputTrainer is implemented by trainers which keep output of the network calculated for last processed sample.
+// It lets training report cost and error rate of training examples without evaluating them again.
+type OutputTrainer interface {
+	Trainer
+	Output() []float64
+}
+
+// BatchTrainer is implemented by trainers which can process whole mini-batch at once, layer by layer.
+// It's needed to train networks with BatchLayer, as then every sample depends on others in the mini-batch.
+// ProcessBatch sets weightUpdates to sum of updates of all samples and returns output of the network
+// for every sample. Outputs are valid until next call.
+type BatchTrainer interface {
+	Trainer
+	ProcessBatch(samples []TrainExample, weightUpdates *WeightUpdates) (outputs [][]float64)
+}
+
 // TrainerFactory build Trainers. Multiple trainers will be created at the beginning of the training.
 type TrainerFactory func(network Evaluator, cost CostDerivative) Trainer
 
@@ -19,38 +35,62 @@ type trainer struct {
 	acticationPerLayer [][]float64
 	potentialsPerLayer [][]float64
 	outError           []float64
+	deltas             [][]float64
 	sp                 [][]float64
 	backward           [][]float64
+
+	// State of ProcessBatch
+	batch         []*batchSample
+	sampleUpdates WeightUpdates
+}
+
+// batchSample keeps signals of a single sample of mini-batch processed by ProcessBatch
+type batchSample struct {
+	layers      []Layer // Own copies of SampleLayers
+	activations [][]float64
+	potentials  [][]float64
+	deltas      [][]float64
+	sp          [][]float64
+	backward    [][]float64 // Delta propagated to input of every layer
 }
 
 // NewBackpropagationTrainer builds new trainer that uses backward propagation algorithm
 func NewBackpropagationTrainer(network Evaluator, cost CostDerivative) Trainer {
 	t := trainer{
 		network: network,
-		layers:  network.Layers(),
+		layers:  make([]Layer, len(network.Layers())),
 		cost:    cost,
 	}
 
+	for l, layer := range network.Layers() {
+		if sample, ok := layer.(SampleLayer); ok {
+			layer = sample.ForSample(Training)
+		}
+		t.layers[l] = layer
+	}
+
 	layersCount := len(t.layers)
 	t.acticationPerLayer = make([][]float64, layersCount+1, layersCount+1)
 	t.potentialsPerLayer = make([][]float64, layersCount, layersCount)
+	t.deltas = make([][]float64, layersCount, layersCount)
 	t.sp = make([][]float64, layersCount, layersCount)
 	t.backward = make([][]float64, layersCount, layersCount)
 
 	for l, layer := range t.layers {
-		_, weightsCol, biasesCol := layer.Shapes()
+		inputs, outputs := layer.Dimensions()
 		if l == 0 {
-			t.acticationPerLayer[0] = make([]float64, weightsCol, weightsCol)
+			t.acticationPerLayer[0] = make([]float64, inputs, inputs)
 		}
 		if l == len(t.layers)-1 {
-			t.outError = make([]float64, biasesCol, biasesCol)
+			t.outError = make([]float64, outputs, outputs)
 		}
 
-		t.acticationPerLayer[l+1] = make([]float64, biasesCol, biasesCol)
-		t.potentialsPerLayer[l] = make([]float64, biasesCol, biasesCol)
-		t.sp[l] = make([]float64, biasesCol, biasesCol)
+		t.acticationPerLayer[l+1] = make([]float64, outputs, outputs)
+		t.potentialsPerLayer[l] = make([]float64, outputs, outputs)
+		t.deltas[l] = make([]float64, outputs, outputs)
+		t.sp[l] = make([]float64, outputs, outputs)
 		if l > 0 {
-			t.backward[l-1] = make([]float64, weightsCol, weightsCol)
+			t.backward[l-1] = make([]float64, inputs, inputs)
 		}
 	}
 
@@ -69,7 +109,7 @@ func (t *trainer) Process(sample TrainExample, weightUpdates *WeightUpdates) {
 	}
 
 	t.cost.CostDerivative(
-		weightUpdates.Biases[lNo],
+		t.deltas[lNo],
 		t.acticationPerLayer[len(t.acticationPerLayer)-1],
 		sample.Output,
 		t.potentialsPerLayer[len(t.potentialsPerLayer)-1],
@@ -77,8 +117,8 @@ func (t *trainer) Process(sample TrainExample, weightUpdates *WeightUpdates) {
 	)
 
 	// Propagate output error to weights of output layer
-	delta := weightUpdates.Biases[lNo]
-	mat.MulTransposeVector(weightUpdates.Weights[lNo], delta, t.acticationPerLayer[len(t.acticationPerLayer)-2])
+	delta := t.deltas[lNo]
+	t.gradients(lNo, weightUpdates)
 
 	for l := 2; l <= layersCount; l++ {
 		lNo = layersCount - l
@@ -87,7 +127,130 @@ func (t *trainer) Process(sample TrainExample, weightUpdates *WeightUpdates) {
 
 		t.layers[lNo+1].Backward(t.backward[lNo], delta)
 
-		delta = mat.MulVectorElementWise(weightUpdates.Biases[lNo], t.backward[lNo], t.sp[lNo])
-		mat.MulTransposeVector(weightUpdates.Weights[lNo], delta, t.acticationPerLayer[len(t.acticationPerLayer)-l-1])
+		delta = mat.MulVectorElementWise(t.deltas[lNo], t.backward[lNo], t.sp[lNo])
+		t.gradients(lNo, weightUpdates)
+	}
+}
+
+// Output returns output of the network for last processed sample
+func (t *trainer) Output() []float64 {
+	return t.acticationPerLayer[len(t.acticationPerLayer)-1]
+}
+
+// gradients asks layer to calculate its weights and biases gradients from its delta and input
+func (t *trainer) gradients(l int, weightUpdates *WeightUpdates) {
+	t.layers[l].Gradients(weightUpdates.Weights[l], weightUpdates.Biases[l], t.deltas[l], t.acticationPerLayer[l])
+}
+
+// ProcessBatch executes backward propagation algorithm for all samples of mini-batch together,
+// so BatchLayers see inputs of the whole mini-batch
+func (t *trainer) ProcessBatch(samples []TrainExample, weightUpdates *WeightUpdates) [][]float64 {
+	t.growBatch(len(samples))
+	batch := t.batch[:len(samples)]
+	layersCount := len(t.layers)
+	lNo := layersCount - 1
+
+	for s, sample := range samples {
+		copy(batch[s].activations[0], sample.Input)
+	}
+
+	for l := range t.layers {
+		if batchLayer, ok := batch[0].layers[l].(BatchLayer); ok {
+			batchLayer.BeginBatch(batchSignals(batch, func(sample *batchSample) []float64 { return sample.activations[l] }))
+		}
+		for _, sample := range batch {
+			layer := sample.layers[l]
+			layer.Forward(sample.potentials[l], sample.activations[l])
+			layer.Activator().Activation(sample.activations[l+1], sample.potentials[l])
+		}
+	}
+
+	outputs := make([][]float64, len(batch), len(batch))
+	for s, sample := range batch {
+		outputs[s] = sample.activations[layersCount]
+		t.cost.CostDerivative(sample.deltas[lNo], outputs[s], samples[s].Output, sample.potentials[lNo], sample.layers[lNo].Activator())
+	}
+
+	for l := lNo; l >= 0; l-- {
+		for s, sample := range batch {
+			updates := &t.sampleUpdates
+			if s == 0 {
+				updates = weightUpdates
+			}
+			sample.layers[l].Gradients(updates.Weights[l], updates.Biases[l], sample.deltas[l], sample.activations[l])
+			if s > 0 {
+				mat.SumMatrix(weightUpdates.Weights[l], updates.Weights[l])
+				mat.SumVector(weightUpdates.Biases[l], updates.Biases[l])
+			}
+		}
+		if l == 0 {
+			break
+		}
+
+		// Propagate delta to layer below
+		if batchLayer, ok := batch[0].layers[l].(BatchLayer); ok {
+			batchLayer.BackwardBatch(
+				batchSignals(batch, func(sample *batchSample) []float64 { return sample.backward[l] }),
+				batchSignals(batch, func(sample *batchSample) []float64 { return sample.deltas[l] }),
+				batchSignals(batch, func(sample *batchSample) []float64 { return sample.activations[l] }),
+			)
+		} else {
+			for _, sample := range batch {
+				sample.layers[l].Backward(sample.backward[l], sample.deltas[l])
+			}
+		}
+
+		for _, sample := range batch {
+			sample.layers[l-1].Activator().Derivative(sample.sp[l-1], sample.potentials[l-1])
+			mat.MulVectorElementWise(sample.deltas[l-1], sample.backward[l], sample.sp[l-1])
+		}
+	}
+
+	return outputs
+}
+
+// growBatch makes sure there is state for given number of samples
+func (t *trainer) growBatch(size int) {
+	if len(t.sampleUpdates.Biases) == 0 {
+		t.sampleUpdates = NewWeightUpdates(t.network)
+	}
+
+	for len(t.batch) < size {
+		sample := batchSample{
+			layers:      make([]Layer, len(t.layers), len(t.layers)),
+			activations: make([][]float64, len(t.layers)+1, len(t.layers)+1),
+			potentials:  make([][]float64, len(t.layers), len(t.layers)),
+			deltas:      make([][]float64, len(t.layers), len(t.layers)),
+			sp:          make([][]float64, len(t.layers), len(t.layers)),
+			backward:    make([][]float64, len(t.layers), len(t.layers)),
+		}
+
+		for l, layer := range t.network.Layers() {
+			if sampleLayer, ok := layer.(SampleLayer); ok {
+				layer = sampleLayer.ForSample(Training)
+			}
+			sample.layers[l] = layer
+
+			inputs, outputs := layer.Dimensions()
+			if l == 0 {
+				sample.activations[0] = make([]float64, inputs, inputs)
+			}
+			sample.activations[l+1] = make([]float64, outputs, outputs)
+			sample.potentials[l] = make([]float64, outputs, outputs)
+			sample.deltas[l] = make([]float64, outputs, outputs)
+			sample.sp[l] = make([]float64, outputs, outputs)
+			sample.backward[l] = make([]float64, inputs, inputs)
+		}
+
+		t.batch = append(t.batch, &sample)
+	}
+}
+
+// batchSignals collects signal of every sample in the batch
+func batchSignals(batch []*batchSample, signal func(sample *batchSample) []float64) [][]float64 {
+	signals := make([][]float64, len(batch), len(batch))
+	for s, sample := range batch {
+		signals[s] = signal(sample)
 	}
+	return signals
 }